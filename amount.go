@@ -0,0 +1,281 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AmountDecimals is the number of decimal places Poloniex uses for prices and quantities
+const AmountDecimals = 8
+
+// amountScale is 10^AmountDecimals, the number of units in 1.0
+const amountScale = 100000000
+
+const (
+	// MaxAmount is the largest Amount, Mul and Div results too large for an Amount saturate to it
+	MaxAmount Amount = math.MaxInt64
+	// MinAmount is the smallest Amount, Mul and Div results too small for an Amount saturate to it
+	MinAmount Amount = math.MinInt64
+)
+
+// Amount is an exact fixed-point decimal with 8 decimal places, stored as a count of 0.00000001 units.
+// It decodes from json strings or numbers and encodes to a json string, the way the API sends them.
+type Amount int64
+
+// ParseAmount parses a decimal string such as "0.00123456", anything past 8 decimal places is rounded half away from zero
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("cannot parse empty amount")
+	}
+	if strings.ContainsAny(s, "eE") {
+		// exponent notation, which json numbers may use
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, errors.Wrap(err, "cannot parse amount "+s)
+		}
+		return amountFromFloat(f)
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, errors.New("cannot parse amount " + s)
+	}
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return 0, errors.New("cannot parse amount " + s)
+		}
+	}
+
+	roundUp := false
+	if len(frac) > AmountDecimals {
+		roundUp = frac[AmountDecimals] >= '5'
+		frac = frac[:AmountDecimals]
+	}
+	frac += strings.Repeat("0", AmountDecimals-len(frac))
+
+	var w, f int64
+	var err error
+	if whole != "" {
+		w, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || w > math.MaxInt64/amountScale-1 {
+			return 0, errors.New("amount out of range " + s)
+		}
+	}
+	f, _ = strconv.ParseInt(frac, 10, 64)
+	units := w*amountScale + f
+	if roundUp {
+		units++
+	}
+	if neg {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+// MustParseAmount is ParseAmount that panics on error, for constants in code
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// maxFloatAmount bounds the floats that convert to an Amount, the whole part of MaxAmount
+const maxFloatAmount = math.MaxInt64 / amountScale
+
+// AmountFromFloat converts f to the nearest Amount. f must be within about ±92233720368,
+// a larger f saturates to MaxAmount or MinAmount and NaN gives zero.
+func AmountFromFloat(f float64) Amount {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f >= maxFloatAmount:
+		return MaxAmount
+	case f <= -maxFloatAmount:
+		return MinAmount
+	}
+	return Amount(math.Round(f * amountScale))
+}
+
+// amountFromFloat is AmountFromFloat that reports NaN and out of range values instead of converting them
+func amountFromFloat(f float64) (Amount, error) {
+	if math.IsNaN(f) || math.Abs(f) >= maxFloatAmount {
+		return 0, errors.Errorf("amount out of range %v", f)
+	}
+	return AmountFromFloat(f), nil
+}
+
+// AmountFromUnits creates an Amount from a count of 0.00000001 units (satoshis for BTC)
+func AmountFromUnits(units int64) Amount {
+	return Amount(units)
+}
+
+// Units returns the amount as a count of 0.00000001 units
+func (a Amount) Units() int64 {
+	return int64(a)
+}
+
+// Decimals returns the number of decimal places needed to write a exactly, from 0 to 8
+func (a Amount) Decimals() int {
+	u := a.Abs() % amountScale
+	if u == 0 {
+		return 0
+	}
+	d := AmountDecimals
+	for u%10 == 0 {
+		u /= 10
+		d--
+	}
+	return d
+}
+
+// Float64 returns the amount as a float, which may not be exact
+func (a Amount) Float64() float64 {
+	return float64(a) / amountScale
+}
+
+// String formats the amount with all 8 decimal places, as the API expects
+func (a Amount) String() string {
+	sign := ""
+	u := uint64(a)
+	if a < 0 {
+		sign = "-"
+		u = uint64(-a)
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, u/amountScale, u%amountScale)
+}
+
+// Add returns a+b
+func (a Amount) Add(b Amount) Amount {
+	return a + b
+}
+
+// Sub returns a-b
+func (a Amount) Sub(b Amount) Amount {
+	return a - b
+}
+
+// Mul returns a*b rounded half away from zero to 8 decimal places, e.g. rate.Mul(amount) for a total.
+// A product outside the range of Amount saturates to MaxAmount or MinAmount.
+func (a Amount) Mul(b Amount) Amount {
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+	return roundQuo(n, big.NewInt(amountScale))
+}
+
+// Div returns a/b rounded half away from zero to 8 decimal places, e.g. total.Div(amount) for a rate.
+// Dividing by zero returns zero, and a quotient outside the range of Amount saturates to MaxAmount or MinAmount.
+func (a Amount) Div(b Amount) Amount {
+	if b == 0 {
+		return 0
+	}
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(amountScale))
+	return roundQuo(n, big.NewInt(int64(b)))
+}
+
+func roundQuo(n, d *big.Int) Amount {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	// compare 2|r| against |d| to round half away from zero
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(new(big.Int).Abs(d)) >= 0 {
+		if (n.Sign() < 0) != (d.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		if q.Sign() < 0 {
+			return MinAmount
+		}
+		return MaxAmount
+	}
+	return Amount(q.Int64())
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// Abs returns |a|
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Cmp returns -1, 0 or 1 when a is less than, equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether a is zero
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of a
+func (a Amount) Sign() int {
+	return a.Cmp(0)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.String() + `"`), nil
+}
+
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	v, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalText(b []byte) error {
+	v, err := ParseAmount(string(b))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,133 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		out  string
+	}{
+		{"0.00000001", 1, "0.00000001"},
+		{"1", 100000000, "1.00000000"},
+		{"12.5", 1250000000, "12.50000000"},
+		{".5", 50000000, "0.50000000"},
+		{"-0.1", -10000000, "-0.10000000"},
+		{"0.123456785", 12345679, "0.12345679"},
+		{"0.123456784", 12345678, "0.12345678"},
+		{"1e-8", 1, "0.00000001"},
+		{"1.5e3", 150000000000, "1500.00000000"},
+	}
+	for _, tt := range tests {
+		a, err := ParseAmount(tt.in)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): %v", tt.in, err)
+			continue
+		}
+		if a != tt.want || a.String() != tt.out {
+			t.Errorf("ParseAmount(%q) = %d (%s), want %d (%s)", tt.in, a, a, tt.want, tt.out)
+		}
+	}
+	for _, in := range []string{"", "abc", "1.2.3", "-", "1,5", "1e15", "1e20", "-1e20", "92233720368", "1e400"} {
+		if _, err := ParseAmount(in); err == nil {
+			t.Errorf("expected an error parsing %q", in)
+		}
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	rate := MustParseAmount("0.03104001")
+	amount := MustParseAmount("2.5")
+	if total := rate.Mul(amount); total.String() != "0.07760003" {
+		t.Errorf("Mul = %s", total)
+	}
+	if r := MustParseAmount("0.07760003").Div(amount); r.String() != "0.03104001" {
+		t.Errorf("Div = %s", r)
+	}
+	if r := MustParseAmount("-1").Div(MustParseAmount("3")); r.String() != "-0.33333333" {
+		t.Errorf("Div negative = %s", r)
+	}
+	if s := rate.Add(amount).Sub(amount); s != rate {
+		t.Errorf("Add/Sub = %s", s)
+	}
+	if rate.Cmp(amount) != -1 || amount.Cmp(rate) != 1 || rate.Cmp(rate) != 0 {
+		t.Error("Cmp")
+	}
+	if AmountFromFloat(0.1+0.2).String() != "0.30000000" {
+		t.Errorf("AmountFromFloat = %s", AmountFromFloat(0.1+0.2))
+	}
+	if AmountFromFloat(1e20) != MaxAmount || AmountFromFloat(-1e20) != MinAmount || AmountFromFloat(math.NaN()) != 0 {
+		t.Errorf("AmountFromFloat out of range = %s, %s, %s", AmountFromFloat(1e20), AmountFromFloat(-1e20), AmountFromFloat(math.NaN()))
+	}
+	if f := amount.Float64(); f != 2.5 {
+		t.Errorf("Float64 = %v", f)
+	}
+}
+
+func TestAmountSaturation(t *testing.T) {
+	big := MustParseAmount("90000000000")
+	if r := big.Mul(big); r != MaxAmount {
+		t.Errorf("Mul overflow = %s, want MaxAmount", r)
+	}
+	if r := big.Neg().Mul(big); r != MinAmount {
+		t.Errorf("Mul negative overflow = %s, want MinAmount", r)
+	}
+	if r := big.Div(MustParseAmount("0.00000001")); r != MaxAmount {
+		t.Errorf("Div overflow = %s, want MaxAmount", r)
+	}
+	if r := big.Neg().Div(MustParseAmount("0.00000001")); r != MinAmount {
+		t.Errorf("Div negative overflow = %s, want MinAmount", r)
+	}
+}
+
+func TestToAmount(t *testing.T) {
+	for _, v := range []interface{}{nil, "1.5", 1.5, int64(1), json.Number("1.5"), MustParseAmount("1.5")} {
+		if _, err := toAmount(v); err != nil {
+			t.Errorf("toAmount(%#v): %v", v, err)
+		}
+	}
+	for _, v := range []interface{}{"", "abc", "1e400", "999999999999", math.NaN(), math.Inf(1), 1e12, int64(math.MaxInt64), true} {
+		if a, err := toAmount(v); err == nil {
+			t.Errorf("toAmount(%#v) = %s, expected an error", v, a)
+		}
+	}
+}
+
+func TestAmountFromFloatRange(t *testing.T) {
+	for _, f := range []float64{1e15, 1e20, -1e20, 92233720368, math.NaN(), math.Inf(-1)} {
+		if a, err := amountFromFloat(f); err == nil {
+			t.Errorf("amountFromFloat(%v) = %s, expected an error", f, a)
+		}
+	}
+	var a Amount
+	if err := json.Unmarshal([]byte("1e20"), &a); err == nil {
+		t.Errorf("decoding 1e20 = %s, expected an error", a)
+	}
+	if a, err := amountFromFloat(-92233720367); err != nil || a >= 0 {
+		t.Errorf("amountFromFloat(-92233720367) = %s, %v", a, err)
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	v := struct {
+		S Amount
+		N Amount
+	}{}
+	if err := json.Unmarshal([]byte(`{"S":"0.00012345","N":0.00344123456}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.S != 12345 || v.N != 344123 {
+		t.Fatalf("unexpected values %+v", v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"S":"0.00012345","N":"0.00344123"}` {
+		t.Fatalf("unexpected json %s", b)
+	}
+}
@@ -3,10 +3,10 @@ package poloniex
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
+	"os"
 	"strconv"
 	"sync"
 	"time"
@@ -21,20 +21,50 @@ import (
 type (
 	//Poloniex describes the API
 	Poloniex struct {
-		Key           string
-		Secret        string
-		ws            recws.RecConn
-		debug         bool
-		nonce         int64
-		mutex         sync.Mutex
-		emitter       *emission.Emitter
-		subscriptions map[string]bool
-		ByID          map[string]string
-		ByName        map[string]string
+		Key                 string
+		Secret              string
+		ws                  wsConn
+		debug               bool
+		nonceSource         NonceSource
+		dispatch            *dispatcher
+		mutex               sync.Mutex
+		emitter             *emission.Emitter
+		subscriptions       map[string]bool
+		wsStarted           bool
+		subscriptionsMutex  sync.Mutex
+		ByID                map[string]string
+		ByName              map[string]string
+		marketsMutex        sync.Mutex
+		lazyMarkets         bool
+		publicURI           string
+		privateURI          string
+		wsURI               string
+		httpClient          *http.Client
+		middleware          []RequestMiddleware
+		logger              *log.Logger
+		limiter             *RateLimiter
+		retryPolicy         RetryPolicy
+		withdrawalDecimals  map[string]int
+		withdrawalAllowlist map[string][]string
+		books               map[string]*LiveOrderBook
+		booksMutex          sync.Mutex
+		sequences           map[string]*channelSequence
+		sequencesMutex      sync.Mutex
+		reorderWindow       time.Duration
+		currencyByID        map[int64]string
+		currenciesMutex     sync.Mutex
+		lastSeen            map[string]time.Time
+		lastMessage         time.Time
+		wsConnected         bool
+		lastSeenMutex       sync.Mutex
+		staleTimeout        time.Duration
 	}
 
-	PoloniexError struct {
-		Error string `json:"error"`
+	//Endpoints holds the addresses a client talks to
+	Endpoints struct {
+		Public    string
+		Private   string
+		Websocket string
 	}
 )
 
@@ -50,53 +80,77 @@ func (p *Poloniex) Debug() {
 	p.debug = true
 }
 
-func (p *Poloniex) getNonce() string {
-	p.nonce++
-	return fmt.Sprintf("%d", p.nonce)
+// Endpoints returns the addresses in use by the client
+func (p *Poloniex) Endpoints() Endpoints {
+	return Endpoints{Public: p.publicURI, Private: p.privateURI, Websocket: p.wsURI}
 }
 
-// NewWithCredentials allows to pass in the key and secret directly
-func NewWithCredentials(key, secret string) *Poloniex {
+func (p *Poloniex) getNonce() (string, error) {
+	n, err := p.nonceSource.Next()
+	if err != nil {
+		return "", errors.Wrap(err, "getting nonce failed")
+	}
+	return fmt.Sprintf("%d", n), nil
+}
+
+// NewClient creates a new client configured by opts.
+// It never dials the websocket (see StartWS), and unless WithLazyMarkets is given it loads the market lookups, returning any error.
+func NewClient(opts ...Option) (*Poloniex, error) {
 	p := &Poloniex{}
-	p.Key = key
-	p.Secret = secret
-	p.nonce = time.Now().UnixNano()
-	p.mutex = sync.Mutex{}
+	p.nonceSource = NewMemoryNonceSource(time.Now().UnixNano())
+	p.dispatch = newDispatcher()
 	p.emitter = emission.NewEmitter()
 	p.subscriptions = map[string]bool{}
-	p.ws = recws.RecConn{}
-	p.ws.Dial(apiURL, http.Header{})
-	p.getMarkets()
+	p.ws = &recws.RecConn{}
+	p.books = map[string]*LiveOrderBook{}
+	p.sequences = map[string]*channelSequence{}
+	p.reorderWindow = DefaultReorderWindow
+	p.lastSeen = map[string]time.Time{}
+	p.staleTimeout = DefaultStaleTimeout
+	p.publicURI = PUBLICURI
+	p.privateURI = PRIVATEURI
+	p.wsURI = apiURL
+	p.httpClient = &http.Client{Timeout: 130 * time.Second}
+	p.logger = log.New(os.Stderr, "", log.LstdFlags)
+	p.limiter = NewRateLimiter(DefaultRequestsPerSecond, DefaultRequestsPerSecond, RateLimitBlock)
+	p.retryPolicy = DefaultRetryPolicy
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, errors.Wrap(err, "applying option failed")
+		}
+	}
+	if !p.lazyMarkets {
+		if err := p.ensureMarkets(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
 
+// NewWithCredentials allows to pass in the key and secret directly
+func NewWithCredentials(key, secret string) *Poloniex {
+	p, err := NewClient(WithCredentials(key, secret))
+	if err != nil {
+		log.Fatalln(err)
+	}
 	return p
 }
 
 // NewWithConfig is the replacement function for New, pass in a configfile to use
 func NewWithConfig(configfile string) *Poloniex {
-	p := map[string]string{}
-	// we have a configfile
-	b, err := ioutil.ReadFile(configfile)
+	p, err := NewClient(WithConfigFile(configfile))
 	if err != nil {
-		log.Fatalln(errors.Wrap(err, "reading "+configfile+" failed."))
+		log.Fatalln(err)
 	}
-	err = json.Unmarshal(b, &p)
-	if err != nil {
-		log.Fatalln(errors.Wrap(err, "unmarshal of config failed."))
-	}
-	return NewWithCredentials(p["key"], p["secret"])
-
+	return p
 }
 
 // NewPublicOnly allows the use of the public and websocket api only
 func NewPublicOnly() *Poloniex {
-	p := &Poloniex{}
-	p.nonce = time.Now().UnixNano()
-	p.mutex = sync.Mutex{}
-	p.emitter = emission.NewEmitter()
-	p.subscriptions = map[string]bool{}
-	p.ws = recws.RecConn{}
-	p.ws.Dial(apiURL, http.Header{})
-	p.getMarkets()
+	p, err := NewClient()
+	if err != nil {
+		log.Fatalln(err)
+	}
 	return p
 }
 
@@ -105,10 +159,20 @@ func New(configfile string) *Poloniex {
 	return NewWithConfig(configfile)
 }
 
-func (p *Poloniex) getMarkets() {
+// ensureMarkets loads the market lookups if they haven't been loaded yet
+func (p *Poloniex) ensureMarkets() error {
+	p.marketsMutex.Lock()
+	defer p.marketsMutex.Unlock()
+	if p.ByID != nil {
+		return nil
+	}
+	return p.getMarkets()
+}
+
+func (p *Poloniex) getMarkets() error {
 	markets, err := p.Ticker()
 	if err != nil {
-		log.Fatalln("error getting markets for lookups", err)
+		return errors.Wrap(err, "error getting markets for lookups")
 	}
 	ByName := map[string]string{}
 	ByID := map[string]string{}
@@ -118,11 +182,13 @@ func (p *Poloniex) getMarkets() {
 		ByID[id] = k
 	}
 
+	ByID["1000"] = "account"
 	ByID["1001"] = "trollbox"
 	ByID["1002"] = "ticker"
 	ByID["1003"] = "footer"
 	ByID["1010"] = "heartbeat"
 
+	ByName["account"] = "1000"
 	ByName["trollbox"] = "1001"
 	ByName["ticker"] = "1002"
 	ByName["footer"] = "1003"
@@ -130,15 +196,16 @@ func (p *Poloniex) getMarkets() {
 
 	p.ByID = ByID
 	p.ByName = ByName
+	return nil
 }
 
 func trace(s string) (string, time.Time) {
 	return s, time.Now()
 }
 
-func un(s string, startTime time.Time) {
+func (p *Poloniex) un(s string, startTime time.Time) {
 	elapsed := time.Since(startTime)
-	log.Printf("trace end: %s, elapsed %f secs\n", s, elapsed.Seconds())
+	p.logger.Printf("trace end: %s, elapsed %f secs\n", s, elapsed.Seconds())
 }
 
 func toFloat(i interface{}) float64 {
@@ -164,10 +231,58 @@ func toFloat(i interface{}) float64 {
 	return maxFloat
 }
 
+func toInt64(i interface{}) int64 {
+	switch i := i.(type) {
+	case float64:
+		return int64(i)
+	case string:
+		n, _ := strconv.ParseInt(i, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// toAmount converts a decoded json value to an Amount, nil (a field that was left off) is zero
+func toAmount(i interface{}) (Amount, error) {
+	switch i := i.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return ParseAmount(i)
+	case float64:
+		return amountFromFloat(i)
+	case int64:
+		if i > math.MaxInt64/amountScale || i < math.MinInt64/amountScale {
+			return 0, errors.Errorf("amount out of range %d", i)
+		}
+		return Amount(i * amountScale), nil
+	case json.Number:
+		return ParseAmount(i.String())
+	case Amount:
+		return i, nil
+	}
+	return 0, errors.Errorf("cannot convert %T to an amount", i)
+}
+
+// amountFields converts several values with toAmount, keeping the first error
+type amountFields struct {
+	err error
+}
+
+func (f *amountFields) parse(i interface{}) Amount {
+	a, err := toAmount(i)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return a
+}
+
 func toString(i interface{}) string {
 	switch i := i.(type) {
 	case string:
 		return i
+	case Amount:
+		return i.String()
 	case float64:
 		return fmt.Sprintf("%.8f", i)
 	case int64:
@@ -0,0 +1,142 @@
+package poloniex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClientMarketsError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"down for maintenance"}`))
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithPublicURL(ts.URL))
+	if err == nil {
+		t.Fatal("expected an error when markets cannot be loaded")
+	}
+	if p != nil {
+		t.Fatal("expected no client on error")
+	}
+}
+
+func TestNewClientLazyMarkets(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"BTC_ETH":{"id":148,"last":"0.1","lowestAsk":"0.1","highestBid":"0.1","percentChange":"0","baseVolume":"1","quoteVolume":"1","isFrozen":"0"}}`))
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithPublicURL(ts.URL), WithLazyMarkets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no calls before markets are needed, got %d", calls)
+	}
+	if err := p.Subscribe("BTC_ETH"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected markets to be loaded once, got %d calls", calls)
+	}
+	if p.ByName["BTC_ETH"] != "148" {
+		t.Fatalf("unexpected market id %q", p.ByName["BTC_ETH"])
+	}
+}
+
+func TestWithBaseURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/public":
+			if r.URL.Query().Get("command") != "returnTicker" {
+				t.Errorf("unexpected public command %q", r.URL.Query().Get("command"))
+			}
+			w.Write([]byte(`{}`))
+		case "/tradingApi":
+			r.ParseForm()
+			if r.Form.Get("command") != "returnCompleteBalances" {
+				t.Errorf("unexpected private command %q", r.Form.Get("command"))
+			}
+			if r.Header.Get("Key") != "key" {
+				t.Errorf("unexpected key header %q", r.Header.Get("Key"))
+			}
+			p := &Poloniex{Secret: "secret"}
+			body := r.Form.Encode()
+			if r.Header.Get("Sign") != p.sign(body) {
+				t.Errorf("bad signature for %q", body)
+			}
+			w.Write([]byte(`{"BTC":{"available":"1.5","onOrders":"0","btcValue":"1.5"}}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithBaseURL(ts.URL), WithCredentials("key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := p.Endpoints(); e.Public != ts.URL+"/public" || e.Private != ts.URL+"/tradingApi" || e.Websocket != apiURL {
+		t.Fatalf("unexpected endpoints %+v", e)
+	}
+	b, err := p.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b["BTC"].Available != MustParseAmount("1.5") {
+		t.Fatalf("unexpected balance %+v", b["BTC"])
+	}
+}
+
+func TestWithEndpointsValidation(t *testing.T) {
+	for _, e := range []Endpoints{
+		{Public: "ftp://localhost/public"},
+		{Private: "localhost/tradingApi"},
+		{Websocket: "http://localhost:8080/"},
+	} {
+		if _, err := NewClient(WithEndpoints(e), WithLazyMarkets()); err == nil {
+			t.Errorf("expected an error for %+v", e)
+		}
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.TickerContext(ctx)
+	if err == nil {
+		t.Fatal("expected an error from a cancelled request")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("unexpected context state %v", ctx.Err())
+	}
+}
+
+func TestPrivateErrorsReturned(t *testing.T) {
+	p, _ := newTestClient(t, map[string]string{
+		"returnAvailableAccountBalances": `{"error":"Invalid API key/secret pair."}`,
+		"returnDepositAddresses":         `{"error":"Invalid API key/secret pair."}`,
+	})
+	if _, err := p.AccountBalancesContext(context.Background()); err == nil {
+		t.Error("expected an error from AccountBalancesContext")
+	}
+	if _, err := p.AddressesContext(context.Background()); err == nil {
+		t.Error("expected an error from AddressesContext")
+	}
+}
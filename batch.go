@@ -0,0 +1,75 @@
+package poloniex
+
+import (
+	"context"
+	"sync"
+)
+
+// batchWorkers is how many calls of a batch are in flight at once, the rate limiter still applies to each
+const batchWorkers = DefaultRequestsPerSecond
+
+type (
+	// OrderResult is the outcome of one order placed by PlaceOrders
+	OrderResult struct {
+		Request OrderRequest
+		Order   Buy
+		Err     error
+	}
+
+	// CancelResult is the outcome of one order cancelled by CancelOrders
+	CancelResult struct {
+		OrderNumber int64
+		Success     bool
+		Err         error
+	}
+)
+
+// PlaceOrders places every order in requests, several at once, and returns a result for each in the same order
+func (p *Poloniex) PlaceOrders(requests []OrderRequest) []OrderResult {
+	return p.PlaceOrdersContext(context.Background(), requests)
+}
+
+// PlaceOrdersContext is PlaceOrders with a context for cancellation and deadlines
+func (p *Poloniex) PlaceOrdersContext(ctx context.Context, requests []OrderRequest) []OrderResult {
+	results := make([]OrderResult, len(requests))
+	fanOut(len(requests), func(i int) {
+		results[i].Request = requests[i]
+		results[i].Order, results[i].Err = p.PlaceOrderContext(ctx, requests[i])
+	})
+	return results
+}
+
+// CancelOrders cancels every order in orderNumbers, several at once, and returns a result for each in the same order
+func (p *Poloniex) CancelOrders(orderNumbers []int64) []CancelResult {
+	return p.CancelOrdersContext(context.Background(), orderNumbers)
+}
+
+// CancelOrdersContext is CancelOrders with a context for cancellation and deadlines
+func (p *Poloniex) CancelOrdersContext(ctx context.Context, orderNumbers []int64) []CancelResult {
+	results := make([]CancelResult, len(orderNumbers))
+	fanOut(len(orderNumbers), func(i int) {
+		results[i].OrderNumber = orderNumbers[i]
+		results[i].Success, results[i].Err = p.CancelOrderContext(ctx, orderNumbers[i])
+	})
+	return results
+}
+
+// fanOut calls fn for 0 to n-1 on up to batchWorkers goroutines, returning once all calls are done
+func fanOut(n int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < batchWorkers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
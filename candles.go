@@ -0,0 +1,125 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Candle5m is a five minute candle
+	Candle5m CandlePeriod = 300
+	// Candle15m is a fifteen minute candle
+	Candle15m CandlePeriod = 900
+	// Candle30m is a thirty minute candle
+	Candle30m CandlePeriod = 1800
+	// Candle2h is a two hour candle
+	Candle2h CandlePeriod = 7200
+	// Candle4h is a four hour candle
+	Candle4h CandlePeriod = 14400
+	// Candle1d is a one day candle
+	Candle1d CandlePeriod = 86400
+)
+
+// chartDataChunk is the most candles ChartDataBackfill asks for in one call
+const chartDataChunk = 1000
+
+// CandlePeriod is the length of a candle in seconds, only the constants above are accepted by the exchange
+type CandlePeriod int
+
+// Validate reports an error unless c is one of the CandlePeriod constants
+func (c CandlePeriod) Validate() error {
+	switch c {
+	case Candle5m, Candle15m, Candle30m, Candle2h, Candle4h, Candle1d:
+		return nil
+	}
+	return errors.Errorf("invalid candle period %d, must be one of 300, 900, 1800, 7200, 14400 or 86400", int(c))
+}
+
+// Duration returns the length of the candle
+func (c CandlePeriod) Duration() time.Duration {
+	return time.Duration(c) * time.Second
+}
+
+func (c CandlePeriod) String() string {
+	return c.Duration().String()
+}
+
+// candlePeriod returns the first of period, or Candle5m if there is none, once validated
+func candlePeriod(period []CandlePeriod) (CandlePeriod, error) {
+	if len(period) == 0 {
+		return Candle5m, nil
+	}
+	return period[0], period[0].Validate()
+}
+
+// ChartDataBackfill returns every candle between start and end, however long the range. The range is fetched in
+// chunks the exchange accepts, and any candles missing between the first and last are filled in flat at the
+// previous close with no volume, so the series has no gaps.
+func (p *Poloniex) ChartDataBackfill(pair string, start, end time.Time, period CandlePeriod) (chartData ChartData, err error) {
+	return p.ChartDataBackfillContext(context.Background(), pair, start, end, period)
+}
+
+// ChartDataBackfillContext is ChartDataBackfill with a context for cancellation and deadlines
+func (p *Poloniex) ChartDataBackfillContext(ctx context.Context, pair string, start, end time.Time, period CandlePeriod) (chartData ChartData, err error) {
+	if err = period.Validate(); err != nil {
+		return
+	}
+	if end.Before(start) {
+		return nil, errors.Errorf("chart data end %s is before start %s", end, start)
+	}
+	step := int64(period)
+	from := start.Unix() / step * step
+	last := end.Unix()
+
+	candles := map[int64]ChartDataEntry{}
+	for from <= last {
+		to := from + (chartDataChunk-1)*step
+		if to > last {
+			to = last
+		}
+		chunk, err := p.ChartDataPeriodContext(ctx, pair, time.Unix(from, 0), time.Unix(to, 0), period)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("fetching candles from %d to %d failed", from, to))
+		}
+		for _, c := range chunk {
+			// an empty range comes back as a single candle dated 0
+			if c.Date.Unix() != 0 {
+				candles[c.Date.Unix()] = c
+			}
+		}
+		from = to + step
+	}
+	return stitchCandles(candles, step), nil
+}
+
+// stitchCandles sorts candles by date and fills any missing periods between the first and last
+func stitchCandles(candles map[int64]ChartDataEntry, step int64) ChartData {
+	dates := make([]int64, 0, len(candles))
+	for d := range candles {
+		dates = append(dates, d)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i] < dates[j] })
+
+	var series ChartData
+	for _, d := range dates {
+		if n := len(series); n > 0 {
+			prev := series[n-1]
+			for gap := prev.Date.Unix() + step; gap < d; gap += step {
+				series = append(series, ChartDataEntry{
+					Date:            time.Unix(gap, 0).UTC(),
+					High:            prev.Close,
+					Low:             prev.Close,
+					Open:            prev.Close,
+					Close:           prev.Close,
+					WeightedAverage: prev.Close,
+				})
+			}
+		}
+		series = append(series, candles[d])
+	}
+	return series
+}
@@ -0,0 +1,66 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestChartDataBackfill(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(5 * 24 * time.Hour)
+
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		from, _ := strconv.ParseInt(r.FormValue("start"), 10, 64)
+		to, _ := strconv.ParseInt(r.FormValue("end"), 10, 64)
+		if r.FormValue("period") != "300" || to-from > (chartDataChunk-1)*300 {
+			t.Errorf("unexpected request %v", r.Form)
+		}
+		candles := []map[string]interface{}{}
+		for d := from; d <= to; d += 300 {
+			// no trades in the tenth hour of each day
+			if time.Unix(d, 0).UTC().Hour() == 10 {
+				continue
+			}
+			candles = append(candles, map[string]interface{}{"date": d, "open": "1", "close": strconv.FormatInt(d, 10), "high": "2", "low": "0.5"})
+		}
+		json.NewEncoder(w).Encode(candles)
+	}))
+	defer ts.Close()
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(nil), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	series, err := p.ChartDataBackfill("BTC_ETH", start, end, Candle5m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 chunks, got %d", calls)
+	}
+	if len(series) != 5*288+1 {
+		t.Fatalf("expected a gap-free series of %d candles, got %d", 5*288+1, len(series))
+	}
+	for i, c := range series {
+		if want := start.Add(time.Duration(i) * 5 * time.Minute); !c.Date.Equal(want) {
+			t.Fatalf("candle %d is dated %s, want %s", i, c.Date, want)
+		}
+	}
+	filled := series[10*12]
+	if !filled.Volume.IsZero() || filled.Open != series[10*12-1].Close || filled.Close != filled.High {
+		t.Fatalf("unexpected filled candle %+v", filled)
+	}
+
+	if _, err := p.ChartDataPeriod("BTC_ETH", start, end, 600); err == nil {
+		t.Fatal("expected an invalid period to be rejected")
+	}
+	if calls != 2 {
+		t.Fatal("an invalid period reached the server")
+	}
+}
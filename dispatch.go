@@ -0,0 +1,63 @@
+package poloniex
+
+import (
+	"context"
+	"sync"
+)
+
+// dispatcher hands out tickets in nonce order and lets each private call send its request only once
+// every earlier ticket has been sent, so nonces reach the exchange in increasing order while the
+// calls still wait for their responses concurrently
+type dispatcher struct {
+	mutex  sync.Mutex
+	issued uint64
+	next   uint64
+	done   map[uint64]bool
+	wake   chan struct{}
+}
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{done: map[uint64]bool{}, wake: make(chan struct{})}
+}
+
+// ticket reserves the next place in the queue, callers must hold the lock used to allocate nonces
+func (d *dispatcher) ticket() uint64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	t := d.issued
+	d.issued++
+	return t
+}
+
+// wait blocks until it is ticket's turn to send, if ctx is done first the ticket is given up
+func (d *dispatcher) wait(ctx context.Context, ticket uint64) error {
+	for {
+		d.mutex.Lock()
+		if d.next == ticket {
+			d.mutex.Unlock()
+			return nil
+		}
+		wake := d.wake
+		d.mutex.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			d.release(ticket)
+			return ctx.Err()
+		}
+	}
+}
+
+// release marks ticket as sent, letting the next one go
+func (d *dispatcher) release(ticket uint64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.done[ticket] = true
+	for d.done[d.next] {
+		delete(d.done, d.next)
+		d.next++
+	}
+	close(d.wake)
+	d.wake = make(chan struct{})
+}
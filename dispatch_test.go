@@ -0,0 +1,100 @@
+package poloniex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcherOrder(t *testing.T) {
+	d := newDispatcher()
+	first, second, third := d.ticket(), d.ticket(), d.ticket()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.wait(ctx, second); err != context.Canceled {
+		t.Fatalf("expected the second ticket to be given up, got %v", err)
+	}
+
+	sent := make(chan uint64, 1)
+	go func() {
+		d.wait(context.Background(), third)
+		sent <- third
+	}()
+	select {
+	case <-sent:
+		t.Fatal("third ticket sent before the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := d.wait(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	d.release(first)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("third ticket not sent after the first was released and the second given up")
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	const calls = 4
+	arrived := make(chan struct{}, 2*calls)
+	proceed := make(chan struct{})
+	var mutex sync.Mutex
+	nonces := map[int64]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tradingApi" {
+			r.ParseForm()
+			n, _ := strconv.ParseInt(r.Form.Get("nonce"), 10, 64)
+			mutex.Lock()
+			if nonces[n] {
+				t.Errorf("nonce %d sent twice", n)
+			}
+			nonces[n] = true
+			mutex.Unlock()
+		}
+		arrived <- struct{}{}
+		<-proceed
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Ticker(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := p.Balances(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// every call has to be in flight at once before any of them is answered
+	for i := 0; i < 2*calls; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d calls in flight at once", i, 2*calls)
+		}
+	}
+	close(proceed)
+	wg.Wait()
+}
@@ -0,0 +1,142 @@
+package poloniex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	simplejson "github.com/bitly/go-simplejson"
+)
+
+// ErrorKind classifies an APIError, it also satisfies error so it can be used as the target of errors.Is
+type ErrorKind int
+
+const (
+	// Unknown is any error that doesn't fit one of the other kinds
+	Unknown ErrorKind = iota
+	// InvalidNonce means the nonce sent was not greater than the last one seen for the key
+	InvalidNonce
+	// InsufficientFunds means the balance is too low for the order, withdrawal or transfer
+	InsufficientFunds
+	// InvalidPair means the currency pair (or currency) is not recognised
+	InvalidPair
+	// RateLimited means too many calls were made in too short a time
+	RateLimited
+	// OrderNotFound means the order does not exist or does not belong to the key
+	OrderNotFound
+	// PermissionDenied means the key or secret is invalid or lacks the permission for the command
+	PermissionDenied
+)
+
+var errorKindNames = map[ErrorKind]string{
+	Unknown:           "unknown",
+	InvalidNonce:      "invalid nonce",
+	InsufficientFunds: "insufficient funds",
+	InvalidPair:       "invalid pair",
+	RateLimited:       "rate limited",
+	OrderNotFound:     "order not found",
+	PermissionDenied:  "permission denied",
+}
+
+func (k ErrorKind) String() string {
+	if s, ok := errorKindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+func (k ErrorKind) Error() string {
+	return k.String()
+}
+
+// errorKindMatches maps fragments of Poloniex error messages (lowercased) to their kind, checked in order
+var errorKindMatches = []struct {
+	fragment string
+	kind     ErrorKind
+}{
+	{"nonce must be greater than", InvalidNonce},
+	{"not enough", InsufficientFunds},
+	{"insufficient", InsufficientFunds},
+	{"invalid currency pair", InvalidPair},
+	{"invalid currencypair", InvalidPair},
+	{"invalid currency", InvalidPair},
+	{"please do not make more than", RateLimited},
+	{"too many requests", RateLimited},
+	{"invalid order number", OrderNotFound},
+	{"not the person who placed the order", OrderNotFound},
+	{"order not found", OrderNotFound},
+	{"invalid api key", PermissionDenied},
+	{"permission", PermissionDenied},
+	{"not allowed", PermissionDenied},
+}
+
+// APIError is an error returned by the Poloniex API, either as an {"error": "..."} body or a failed http status
+type APIError struct {
+	Command    string
+	StatusCode int
+	Message    string `json:"error"`
+	Kind       ErrorKind
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("poloniex: %s: %s", e.Command, e.Message)
+}
+
+// Is reports whether target is the same ErrorKind as e, or an *APIError of the same kind
+func (e *APIError) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorKind:
+		return e.Kind == t
+	case *APIError:
+		return e.Kind == t.Kind
+	}
+	return false
+}
+
+func newAPIError(command string, statusCode int, message string) *APIError {
+	e := &APIError{Command: command, StatusCode: statusCode, Message: message}
+	e.Kind = classifyError(statusCode, message)
+	return e
+}
+
+func classifyError(statusCode int, message string) ErrorKind {
+	m := strings.ToLower(message)
+	for _, match := range errorKindMatches {
+		if strings.Contains(m, match.fragment) {
+			return match.kind
+		}
+	}
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return PermissionDenied
+	}
+	return Unknown
+}
+
+// checkResponse turns an error body or a failed http status into an *APIError
+func checkResponse(command string, statusCode int, body []byte) error {
+	jsonData, err := simplejson.NewJson(body)
+	if err != nil {
+		if statusCode >= 400 {
+			message := strings.TrimSpace(string(body))
+			if message == "" {
+				message = http.StatusText(statusCode)
+			}
+			return newAPIError(command, statusCode, message)
+		}
+		return err
+	}
+
+	// do we have an error message from the server?
+	jsonErr, ok := jsonData.CheckGet("error")
+	if ok {
+		// looks like we have an error from poloniex
+		return newAPIError(command, statusCode, jsonErr.MustString())
+	}
+	if statusCode >= 400 {
+		return newAPIError(command, statusCode, http.StatusText(statusCode))
+	}
+	return nil
+}
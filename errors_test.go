@@ -0,0 +1,65 @@
+package poloniex
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		kind    ErrorKind
+	}{
+		{200, "Nonce must be greater than 1526486223396000. You provided 1526486223395000.", InvalidNonce},
+		{200, "Not enough BTC.", InsufficientFunds},
+		{200, "Invalid currency pair.", InvalidPair},
+		{200, "Please do not make more than 8 API calls per second.", RateLimited},
+		{429, "", RateLimited},
+		{200, "Invalid order number, or you are not the person who placed the order.", OrderNotFound},
+		{200, "Invalid API key/secret pair.", PermissionDenied},
+		{403, "Forbidden", PermissionDenied},
+		{200, "Total must be at least 0.0001.", Unknown},
+	}
+	for _, tt := range tests {
+		if k := classifyError(tt.status, tt.message); k != tt.kind {
+			t.Errorf("classifyError(%d, %q) = %s, want %s", tt.status, tt.message, k, tt.kind)
+		}
+	}
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/public" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html>down</html>"))
+			return
+		}
+		w.Write([]byte(`{"error":"Not enough BTC."}`))
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = p.Buy("BTC_ETH", 0.1, 1)
+	if !errors.Is(err, InsufficientFunds) {
+		t.Fatalf("expected insufficient funds, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %T", err)
+	}
+	if apiErr.Command != "buy" || apiErr.Message != "Not enough BTC." || apiErr.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected error %+v", apiErr)
+	}
+
+	_, err = p.Ticker()
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Kind != Unknown {
+		t.Fatalf("unexpected error %#v", err)
+	}
+}
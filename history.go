@@ -0,0 +1,204 @@
+package poloniex
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// publicTradeHistoryLimit is the most trades the public returnTradeHistory sends for one range
+	publicTradeHistoryLimit = 1000
+	// privateTradeHistoryLimit is the most trades the private returnTradeHistory sends for one range
+	privateTradeHistoryLimit = 10000
+)
+
+// ErrHistoryTruncated is returned by a trade history iterator when a single second holds more trades than the
+// exchange sends for one call. The trades of that second that were sent have been returned, the rest cannot be reached.
+var ErrHistoryTruncated = errors.New("poloniex: trade history truncated")
+
+// errNoStart stops a history iterator given a zero start, which would walk back a window at a time to year 1
+var errNoStart = errors.New("poloniex: history iterators need a start time")
+
+type (
+	// tradeWindows splits a range into windows walked from newest to oldest, paging back through a window
+	// while the exchange keeps returning full pages
+	tradeWindows struct {
+		ctx    context.Context
+		start  time.Time
+		lo, hi time.Time
+		window time.Duration
+		limit  int
+		done   bool
+		err    error
+		// globalTradeIDs in the previous page, which overlaps the next one by a second
+		seen map[int64]bool
+	}
+
+	// TradeHistoryIterator streams public trades from newest to oldest, in the style of bufio.Scanner
+	TradeHistoryIterator struct {
+		tradeWindows
+		p     *Poloniex
+		pair  string
+		page  TradeHistory
+		trade TradeHistoryEntry
+	}
+
+	// PrivateTradeHistoryIterator streams your trades in a pair from newest to oldest, in the style of bufio.Scanner
+	PrivateTradeHistoryIterator struct {
+		tradeWindows
+		p     *Poloniex
+		pair  string
+		page  PrivateTradeHistory
+		trade PrivateTradeHistoryEntry
+	}
+)
+
+func newTradeWindows(ctx context.Context, start, end time.Time, limit int) tradeWindows {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	w := tradeWindows{ctx: ctx, start: start, hi: end, window: DefaultHistoryWindow, limit: limit}
+	w.lo = w.hi.Add(-w.window)
+	if w.lo.Before(start) {
+		w.lo = start
+	}
+	w.done = end.Before(start)
+	if start.IsZero() {
+		w.err = errNoStart
+	}
+	return w
+}
+
+// advance records a page of n trades, returning which are new, and moves on to the next range to fetch
+func (w *tradeWindows) advance(n int, trade func(i int) (id int64, date time.Time)) []int {
+	var fresh []int
+	seen := make(map[int64]bool, n)
+	oldest := w.hi
+	for i := 0; i < n; i++ {
+		id, date := trade(i)
+		seen[id] = true
+		if !w.seen[id] {
+			fresh = append(fresh, i)
+		}
+		if date.Before(oldest) {
+			oldest = date
+		}
+	}
+	w.seen = seen
+
+	if n >= w.limit {
+		// saturated, page back from the oldest trade returned
+		next := oldest
+		if !next.Before(w.hi) {
+			// a whole page within one second, the rest of that second cannot be reached
+			w.err = errors.Wrapf(ErrHistoryTruncated, "at least %d trades at %s", n, w.hi.UTC().Format(poloniexDateFormat))
+			return fresh
+		}
+		if !next.Before(w.lo) {
+			w.hi = next
+			return fresh
+		}
+	}
+
+	if !w.lo.After(w.start) {
+		w.done = true
+		return fresh
+	}
+	w.hi = w.lo
+	w.lo = w.lo.Add(-w.window)
+	if w.lo.Before(w.start) {
+		w.lo = w.start
+	}
+	return fresh
+}
+
+// TradeHistoryIter returns an iterator over every public trade in pair between start and end, newest first.
+// A zero end means now, a zero start is an error returned by Err. Trades are fetched in windows that are paged back through when the exchange's
+// per call limit is reached, and trades returned twice on page boundaries are dropped. If one second holds
+// more trades than a page the iterator stops after them with ErrHistoryTruncated.
+func (p *Poloniex) TradeHistoryIter(pair string, start, end time.Time) *TradeHistoryIterator {
+	return p.TradeHistoryIterContext(context.Background(), pair, start, end)
+}
+
+// TradeHistoryIterContext is TradeHistoryIter with a context for cancellation and deadlines
+func (p *Poloniex) TradeHistoryIterContext(ctx context.Context, pair string, start, end time.Time) *TradeHistoryIterator {
+	return &TradeHistoryIterator{tradeWindows: newTradeWindows(ctx, start, end, publicTradeHistoryLimit), p: p, pair: pair}
+}
+
+// Next moves to the next trade, fetching more as needed, it returns false when there are no more or on error
+func (it *TradeHistoryIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		page, err := it.p.TradeHistoryContext(it.ctx, it.pair, it.lo.Unix(), it.hi.Unix())
+		if err != nil {
+			it.err = err
+			return false
+		}
+		for _, i := range it.advance(len(page), func(i int) (int64, time.Time) { return page[i].ID, page[i].Date }) {
+			it.page = append(it.page, page[i])
+		}
+	}
+	it.trade, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Trade returns the trade reached by the last successful call to Next
+func (it *TradeHistoryIterator) Trade() TradeHistoryEntry {
+	return it.trade
+}
+
+// Err returns the error that stopped the iterator, if any
+func (it *TradeHistoryIterator) Err() error {
+	return it.err
+}
+
+// PrivateTradeHistoryIter returns an iterator over your trades in pair between start and end, newest first,
+// fetched the same way as TradeHistoryIter
+func (p *Poloniex) PrivateTradeHistoryIter(pair string, start, end time.Time) *PrivateTradeHistoryIterator {
+	return p.PrivateTradeHistoryIterContext(context.Background(), pair, start, end)
+}
+
+// PrivateTradeHistoryIterContext is PrivateTradeHistoryIter with a context for cancellation and deadlines
+func (p *Poloniex) PrivateTradeHistoryIterContext(ctx context.Context, pair string, start, end time.Time) *PrivateTradeHistoryIterator {
+	return &PrivateTradeHistoryIterator{tradeWindows: newTradeWindows(ctx, start, end, privateTradeHistoryLimit), p: p, pair: pair}
+}
+
+// Next moves to the next trade, fetching more as needed, it returns false when there are no more or on error
+func (it *PrivateTradeHistoryIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		params := url.Values{}
+		params.Add("currencyPair", it.pair)
+		params.Add("start", fmt.Sprintf("%d", it.lo.Unix()))
+		params.Add("end", fmt.Sprintf("%d", it.hi.Unix()))
+		params.Add("limit", fmt.Sprintf("%d", it.limit))
+		var page PrivateTradeHistory
+		if err := it.p.private(it.ctx, "returnTradeHistory", params, &page); err != nil {
+			it.err = err
+			return false
+		}
+		for _, i := range it.advance(len(page), func(i int) (int64, time.Time) { return page[i].GlobalTradeID, page[i].Date }) {
+			it.page = append(it.page, page[i])
+		}
+	}
+	it.trade, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Trade returns the trade reached by the last successful call to Next
+func (it *PrivateTradeHistoryIterator) Trade() PrivateTradeHistoryEntry {
+	return it.trade
+}
+
+// Err returns the error that stopped the iterator, if any
+func (it *PrivateTradeHistoryIterator) Err() error {
+	return it.err
+}
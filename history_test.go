@@ -0,0 +1,124 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestTradeHistoryIter(t *testing.T) {
+	// 2500 trades, three to a second, spread over the end of a 40 day range
+	end := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	start := end.Add(-40 * 24 * time.Hour)
+	var trades TradeHistory
+	for i := 0; i < 2500; i++ {
+		date := start.Add(time.Duration(i/3) * time.Second)
+		if i >= 1500 {
+			date = end.Add(-time.Duration(2500-i) * time.Second / 3)
+		}
+		trades = append(trades, TradeHistoryEntry{ID: int64(i + 1), Date: date.Truncate(time.Second), Type: SideBuy})
+	}
+
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		from, _ := strconv.ParseInt(r.FormValue("start"), 10, 64)
+		to, _ := strconv.ParseInt(r.FormValue("end"), 10, 64)
+		var page []map[string]interface{}
+		for i := len(trades) - 1; i >= 0; i-- {
+			trade := trades[i]
+			if trade.Date.Unix() >= from && trade.Date.Unix() <= to {
+				page = append(page, map[string]interface{}{
+					"globalTradeID": trade.ID, "date": trade.Date.Format(poloniexDateFormat), "type": "buy",
+					"rate": "0.1", "amount": "1", "total": "0.1",
+				})
+			}
+		}
+		if len(page) > publicTradeHistoryLimit {
+			page = page[:publicTradeHistoryLimit]
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer ts.Close()
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(nil), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it := p.TradeHistoryIter("BTC_ETH", start, end)
+	var got []int64
+	for it.Next() {
+		got = append(got, it.Trade().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(trades) {
+		t.Fatalf("got %d trades from %d calls, want %d", len(got), calls, len(trades))
+	}
+	for i, id := range got {
+		if id != int64(len(trades)-i) {
+			t.Fatalf("trade %d has id %d, want newest first without repeats", i, id)
+		}
+	}
+	if calls < 4 {
+		t.Fatalf("expected saturated windows to be paged, only %d calls", calls)
+	}
+}
+
+func TestTradeHistoryIterTruncated(t *testing.T) {
+	// a full page of trades all in the last second of the range
+	end := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page []map[string]interface{}
+		for i := 0; i < publicTradeHistoryLimit; i++ {
+			page = append(page, map[string]interface{}{
+				"globalTradeID": publicTradeHistoryLimit - i, "date": end.Format(poloniexDateFormat), "type": "buy",
+				"rate": "0.1", "amount": "1", "total": "0.1",
+			})
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer ts.Close()
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(nil), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	it := p.TradeHistoryIter("BTC_ETH", end.Add(-time.Hour), end)
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != publicTradeHistoryLimit {
+		t.Fatalf("got %d trades, want the %d sent", n, publicTradeHistoryLimit)
+	}
+	if errors.Cause(it.Err()) != ErrHistoryTruncated {
+		t.Fatalf("expected ErrHistoryTruncated, got %v", it.Err())
+	}
+}
+
+func TestHistoryIterZeroStart(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{})
+	end := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	for name, it := range map[string]interface {
+		Next() bool
+		Err() error
+	}{
+		"trades":         p.TradeHistoryIter("BTC_ETH", time.Time{}, end),
+		"private trades": p.PrivateTradeHistoryIter("BTC_ETH", time.Time{}, end),
+		"transfers":      p.DepositsWithdrawalsIter(time.Time{}, end, 0),
+	} {
+		if it.Next() || it.Err() != errNoStart {
+			t.Errorf("%s: expected a zero start to be rejected, got %v", name, it.Err())
+		}
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("expected no calls, got %d", len(*calls))
+	}
+}
@@ -0,0 +1,168 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// OrderTypeExchange is a trade made on the exchange account
+	OrderTypeExchange OrderType = "exchange"
+	// OrderTypeMargin is a trade made on margin
+	OrderTypeMargin OrderType = "marginTrade"
+	// OrderTypeSettlement is a trade made to settle a margin position
+	OrderTypeSettlement OrderType = "settlement"
+)
+
+const (
+	// AccountExchange is the account used for spot trading
+	AccountExchange Account = "exchange"
+	// AccountMargin is the account used for margin trading
+	AccountMargin Account = "margin"
+	// AccountLending is the account used for lending
+	AccountLending Account = "lending"
+)
+
+type (
+	// OrderType is the category of a trade in your history. Values the exchange sends are kept as they are,
+	// Validate reports whether one is known.
+	OrderType string
+
+	// Account is one of the wallets balances are held in. Values the exchange sends are kept as they are,
+	// Validate reports whether one is known.
+	Account string
+)
+
+// Validate reports an error unless t is one of the OrderType constants
+func (t OrderType) Validate() error {
+	switch t {
+	case OrderTypeExchange, OrderTypeMargin, OrderTypeSettlement:
+		return nil
+	}
+	return errors.Errorf("invalid order type %q", string(t))
+}
+
+// Validate reports an error unless a is one of the Account constants
+func (a Account) Validate() error {
+	switch a {
+	case AccountExchange, AccountMargin, AccountLending:
+		return nil
+	}
+	return errors.Errorf("invalid account %q", string(a))
+}
+
+// parseDate parses a REST API date string, which is always in UTC, an empty string gives the zero time
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(poloniexDateFormat, s)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parsing date failed")
+	}
+	return t, nil
+}
+
+// the decoders below read the date strings into time.Time, each through an alias type
+// so the remaining fields decode as usual
+
+func (e *TradeHistoryEntry) UnmarshalJSON(b []byte) (err error) {
+	type tradeHistoryEntry TradeHistoryEntry
+	raw := struct {
+		*tradeHistoryEntry
+		Date string
+	}{tradeHistoryEntry: (*tradeHistoryEntry)(e)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+	e.Date, err = parseDate(raw.Date)
+	return
+}
+
+func (e *ChartDataEntry) UnmarshalJSON(b []byte) error {
+	type chartDataEntry ChartDataEntry
+	raw := struct {
+		*chartDataEntry
+		Date int64
+	}{chartDataEntry: (*chartDataEntry)(e)}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	e.Date = time.Unix(raw.Date, 0).UTC()
+	return nil
+}
+
+func (e *PrivateTradeHistoryEntry) UnmarshalJSON(b []byte) (err error) {
+	type privateTradeHistoryEntry PrivateTradeHistoryEntry
+	raw := struct {
+		*privateTradeHistoryEntry
+		Date string
+	}{privateTradeHistoryEntry: (*privateTradeHistoryEntry)(e)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+	e.Date, err = parseDate(raw.Date)
+	return
+}
+
+func (ot *OrderTrade) UnmarshalJSON(b []byte) (err error) {
+	type orderTrade OrderTrade
+	raw := struct {
+		*orderTrade
+		Date string `json:"date"`
+	}{orderTrade: (*orderTrade)(ot)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+	ot.Date, err = parseDate(raw.Date)
+	return
+}
+
+func (o *OpenLoanOffer) UnmarshalJSON(b []byte) (err error) {
+	type openLoanOffer OpenLoanOffer
+	raw := struct {
+		*openLoanOffer
+		Date string
+	}{openLoanOffer: (*openLoanOffer)(o)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+	o.Renewable = o.AutoRenew == 1
+	o.Date, err = parseDate(raw.Date)
+	o.DateTaken = o.Date
+	return
+}
+
+func (l *ActiveLoan) UnmarshalJSON(b []byte) (err error) {
+	type activeLoan ActiveLoan
+	raw := struct {
+		*activeLoan
+		Date string
+	}{activeLoan: (*activeLoan)(l)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+	l.Renewable = l.AutoRenew == 1
+	l.Date, err = parseDate(raw.Date)
+	l.DateTaken = l.Date
+	return
+}
+
+func (e *LendingHistoryEntry) UnmarshalJSON(b []byte) (err error) {
+	type lendingHistoryEntry LendingHistoryEntry
+	raw := struct {
+		*lendingHistoryEntry
+		Open  string
+		Close string
+	}{lendingHistoryEntry: (*lendingHistoryEntry)(e)}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return
+	}
+	if e.Open, err = parseDate(raw.Open); err != nil {
+		return
+	}
+	e.Close, err = parseDate(raw.Close)
+	return
+}
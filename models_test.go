@@ -0,0 +1,68 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestModelDecoding(t *testing.T) {
+	var trades TradeHistory
+	err := json.Unmarshal([]byte(`[{"globalTradeID":25129732,"tradeID":"6325758","date":"2016-04-05 08:08:40","type":"Sell","rate":"0.02565498","amount":"0.10000000","total":"0.00256549"}]`), &trades)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trades[0].Type != SideSell || !trades[0].Date.Equal(time.Date(2016, 4, 5, 8, 8, 40, 0, time.UTC)) || trades[0].Date.Location() != time.UTC {
+		t.Fatalf("unexpected trade %+v", trades[0])
+	}
+
+	var candles ChartData
+	if err := json.Unmarshal([]byte(`[{"date":1405699200,"high":0.0045388,"low":0.00403001,"open":0.00404545,"close":0.00427592}]`), &candles); err != nil {
+		t.Fatal(err)
+	}
+	if !candles[0].Date.Equal(time.Unix(1405699200, 0)) || candles[0].Date.Location() != time.UTC {
+		t.Fatalf("unexpected candle %+v", candles[0])
+	}
+
+	var history PrivateTradeHistory
+	err = json.Unmarshal([]byte(`[{"globalTradeID":25129732,"date":"2016-04-05 08:08:40","rate":"0.02565498","amount":"0.10000000","total":"0.00256549",
+		"fee":"0.00200000","orderNumber":"12603322113","type":"buy","category":"settlement"}]`), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history[0].Category != OrderTypeSettlement || history[0].Date.IsZero() {
+		t.Fatalf("unexpected entry %+v", history[0])
+	}
+
+	if err := json.Unmarshal([]byte(`[{"date":"05/04/2016"}]`), &trades); err == nil {
+		t.Error("expected a malformed date to be rejected")
+	}
+
+	// values the exchange adds later are kept rather than failing the whole response
+	if err := json.Unmarshal([]byte(`[{"type":"hold"}]`), &trades); err != nil || trades[0].Type != "hold" || trades[0].Type.Validate() == nil {
+		t.Errorf("unexpected side %q, err %v", trades[0].Type, err)
+	}
+	if err := json.Unmarshal([]byte(`[{"category":"spot"}]`), &history); err != nil || history[0].Category != "spot" || history[0].Category.Validate() == nil {
+		t.Errorf("unexpected category %q, err %v", history[0].Category, err)
+	}
+	var update struct{ Wallet Account }
+	if err := json.Unmarshal([]byte(`{"wallet":"futures"}`), &update); err != nil || update.Wallet != "futures" || update.Wallet.Validate() == nil {
+		t.Errorf("unexpected account %q, err %v", update.Wallet, err)
+	}
+}
+
+func TestTransferBalanceAccounts(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{
+		"transferBalance": `{"success":1,"message":"Transferred 2.00000000 BTC from exchange to margin account."}`,
+	})
+	if _, err := p.TransferBalance("BTC", 2, AccountExchange, "savings"); err == nil {
+		t.Fatal("expected an unknown account to be rejected")
+	}
+	tb, err := p.TransferBalance("BTC", 2, AccountExchange, AccountMargin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.Success != 1 || len(*calls) != 1 || (*calls)[0].Get("toAccount") != "margin" {
+		t.Fatalf("unexpected transfer %+v, calls %v", tb, *calls)
+	}
+}
@@ -0,0 +1,164 @@
+package poloniex
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// NonceSource hands out the increasing nonces used to sign private calls
+	NonceSource interface {
+		// Next returns a nonce greater than any returned before
+		Next() (int64, error)
+		// Advance makes sure later nonces are greater than n
+		Advance(n int64) error
+	}
+
+	// MemoryNonceSource is a counter held in memory, it is only safe for a single process per key
+	MemoryNonceSource struct {
+		mutex sync.Mutex
+		nonce int64
+	}
+
+	// MonotonicNonceSource derives nonces from the monotonic clock, so wall clock adjustments
+	// made while the process runs cannot move nonces backwards
+	MonotonicNonceSource struct {
+		mutex sync.Mutex
+		start time.Time
+		last  int64
+	}
+
+	// FileNonceSource keeps the last nonce in a file, locked while in use,
+	// so several processes on one host can share a key
+	FileNonceSource struct {
+		mutex sync.Mutex
+		path  string
+	}
+)
+
+// NewMemoryNonceSource creates an in-memory counter starting after start
+func NewMemoryNonceSource(start int64) *MemoryNonceSource {
+	return &MemoryNonceSource{nonce: start}
+}
+
+// Next increments the counter and returns it
+func (m *MemoryNonceSource) Next() (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nonce++
+	return m.nonce, nil
+}
+
+// Advance moves the counter up to n if it is behind
+func (m *MemoryNonceSource) Advance(n int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.nonce < n {
+		m.nonce = n
+	}
+	return nil
+}
+
+// NewMonotonicNonceSource creates a nonce source based on nanoseconds since the unix epoch
+func NewMonotonicNonceSource() *MonotonicNonceSource {
+	return &MonotonicNonceSource{start: time.Now()}
+}
+
+// Next returns the current time in nanoseconds, or one more than the last nonce if that is not greater
+func (m *MonotonicNonceSource) Next() (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	// time.Since uses the monotonic reading taken in NewMonotonicNonceSource
+	n := m.start.UnixNano() + int64(time.Since(m.start))
+	if n <= m.last {
+		n = m.last + 1
+	}
+	m.last = n
+	return n, nil
+}
+
+// Advance makes sure the next nonce is greater than n, even if the clock is behind it
+func (m *MonotonicNonceSource) Advance(n int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.last < n {
+		m.last = n
+	}
+	return nil
+}
+
+// NewFileNonceSource creates a nonce source stored in path, the file is created if needed
+func NewFileNonceSource(path string) (*FileNonceSource, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening nonce file failed")
+	}
+	f.Close()
+	return &FileNonceSource{path: path}, nil
+}
+
+// Next returns the larger of the stored nonce plus one and the current time in nanoseconds, and stores it
+func (fs *FileNonceSource) Next() (int64, error) {
+	var n int64
+	err := fs.update(func(stored int64) int64 {
+		n = time.Now().UnixNano()
+		if n <= stored {
+			n = stored + 1
+		}
+		return n
+	})
+	return n, err
+}
+
+// Advance stores n if it is greater than the stored nonce
+func (fs *FileNonceSource) Advance(n int64) error {
+	return fs.update(func(stored int64) int64 {
+		if stored < n {
+			return n
+		}
+		return stored
+	})
+}
+
+// update reads the stored nonce and writes back the result of fn, holding the file lock throughout
+func (fs *FileNonceSource) update(fn func(stored int64) int64) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	f, err := os.OpenFile(fs.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "opening nonce file failed")
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return errors.Wrap(err, "locking nonce file failed")
+	}
+	defer unlockFile(f)
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return errors.Wrap(err, "reading nonce file failed")
+	}
+	var stored int64
+	if s := strings.TrimSpace(string(b)); s != "" {
+		stored, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "parsing nonce file failed")
+		}
+	}
+
+	n := fn(stored)
+	if err := f.Truncate(0); err != nil {
+		return errors.Wrap(err, "writing nonce file failed")
+	}
+	if _, err := f.WriteAt([]byte(strconv.FormatInt(n, 10)), 0); err != nil {
+		return errors.Wrap(err, "writing nonce file failed")
+	}
+	return f.Sync()
+}
@@ -0,0 +1,77 @@
+package poloniex
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFileNonceSourceShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nonce")
+	a, err := NewFileNonceSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewFileNonceSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	seen := map[int64]bool{}
+	var wg sync.WaitGroup
+	for _, source := range []NonceSource{a, b} {
+		wg.Add(1)
+		go func(source NonceSource) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				n, err := source.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mutex.Lock()
+				if seen[n] {
+					t.Errorf("nonce %d handed out twice", n)
+				}
+				seen[n] = true
+				mutex.Unlock()
+			}
+		}(source)
+	}
+	wg.Wait()
+
+	if err := a.Advance(1 << 62); err != nil {
+		t.Fatal(err)
+	}
+	n, err := b.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n <= 1<<62 {
+		t.Fatalf("expected nonce past the advanced value, got %d", n)
+	}
+}
+
+func TestNonceSourcesIncrease(t *testing.T) {
+	for name, source := range map[string]NonceSource{
+		"memory":    NewMemoryNonceSource(0),
+		"monotonic": NewMonotonicNonceSource(),
+	} {
+		var last int64
+		for i := 0; i < 1000; i++ {
+			n, err := source.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n <= last {
+				t.Fatalf("%s: nonce %d not greater than %d", name, n, last)
+			}
+			last = n
+		}
+		source.Advance(last + 1000)
+		if n, _ := source.Next(); n <= last+1000 {
+			t.Fatalf("%s: nonce %d not past advanced value", name, n)
+		}
+	}
+}
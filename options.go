@@ -0,0 +1,258 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Option configures a client created by NewClient
+type Option func(*Poloniex) error
+
+// WithCredentials sets the API key and secret used for the private API
+func WithCredentials(key, secret string) Option {
+	return func(p *Poloniex) error {
+		p.Key = key
+		p.Secret = secret
+		return nil
+	}
+}
+
+// WithConfigFile reads the API key and secret from a json config file, see config-example.json
+func WithConfigFile(configfile string) Option {
+	return func(p *Poloniex) error {
+		c := map[string]string{}
+		b, err := ioutil.ReadFile(configfile)
+		if err != nil {
+			return errors.Wrap(err, "reading "+configfile+" failed.")
+		}
+		err = json.Unmarshal(b, &c)
+		if err != nil {
+			return errors.Wrap(err, "unmarshal of config failed.")
+		}
+		p.Key = c["key"]
+		p.Secret = c["secret"]
+		return nil
+	}
+}
+
+// WithPublicURL overrides the address of the public REST API
+func WithPublicURL(uri string) Option {
+	return func(p *Poloniex) error {
+		if err := validateURL(uri, "http", "https"); err != nil {
+			return errors.Wrap(err, "invalid public url")
+		}
+		p.publicURI = uri
+		return nil
+	}
+}
+
+// WithPrivateURL overrides the address of the private (trading) REST API
+func WithPrivateURL(uri string) Option {
+	return func(p *Poloniex) error {
+		if err := validateURL(uri, "http", "https"); err != nil {
+			return errors.Wrap(err, "invalid private url")
+		}
+		p.privateURI = uri
+		return nil
+	}
+}
+
+// WithWebsocketURL overrides the address of the websocket API
+func WithWebsocketURL(uri string) Option {
+	return func(p *Poloniex) error {
+		if err := validateURL(uri, "ws", "wss"); err != nil {
+			return errors.Wrap(err, "invalid websocket url")
+		}
+		p.wsURI = uri
+		return nil
+	}
+}
+
+// WithEndpoints overrides all the addresses at once, empty fields keep their current value
+func WithEndpoints(e Endpoints) Option {
+	return func(p *Poloniex) error {
+		if e.Public != "" {
+			if err := WithPublicURL(e.Public)(p); err != nil {
+				return err
+			}
+		}
+		if e.Private != "" {
+			if err := WithPrivateURL(e.Private)(p); err != nil {
+				return err
+			}
+		}
+		if e.Websocket != "" {
+			if err := WithWebsocketURL(e.Websocket)(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithBaseURL points the REST API at a single server laid out like poloniex.com,
+// i.e. base+"/public" and base+"/tradingApi", which suits a local stand-in such as an httptest.Server
+func WithBaseURL(base string) Option {
+	base = strings.TrimRight(base, "/")
+	return WithEndpoints(Endpoints{Public: base + "/public", Private: base + "/tradingApi"})
+}
+
+// WithHTTPClient sets the http.Client used for all REST calls
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Poloniex) error {
+		if client == nil {
+			return errors.New("http client must not be nil")
+		}
+		p.httpClient = client
+		return nil
+	}
+}
+
+// WithTransport sets the http.RoundTripper used for all REST calls, e.g. for proxies, custom TLS or test doubles
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *Poloniex) error {
+		if transport == nil {
+			return errors.New("transport must not be nil")
+		}
+		// copy the client so one passed to WithHTTPClient isn't changed
+		c := *p.httpClient
+		c.Transport = transport
+		p.httpClient = &c
+		return nil
+	}
+}
+
+// WithRequestMiddleware adds hooks called in order with every REST request before it is sent
+func WithRequestMiddleware(middleware ...RequestMiddleware) Option {
+	return func(p *Poloniex) error {
+		p.middleware = append(p.middleware, middleware...)
+		return nil
+	}
+}
+
+// WithLogger sets the logger used for websocket and debug output
+func WithLogger(logger *log.Logger) Option {
+	return func(p *Poloniex) error {
+		if logger == nil {
+			return errors.New("logger must not be nil")
+		}
+		p.logger = logger
+		return nil
+	}
+}
+
+// WithRateLimiter sets the limiter applied to all REST calls, pass the same limiter to several clients to share it.
+// A nil limiter turns rate limiting off.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(p *Poloniex) error {
+		p.limiter = limiter
+		return nil
+	}
+}
+
+// WithRetryPolicy sets how failed REST calls are retried, RetryPolicy{} turns retries off
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Poloniex) error {
+		if policy.Jitter < 0 || policy.Jitter > 1 {
+			return errors.New("retry jitter must be between 0 and 1")
+		}
+		p.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithNonceSource sets where nonces for private calls come from, see NewFileNonceSource for sharing a key between processes
+func WithNonceSource(source NonceSource) Option {
+	return func(p *Poloniex) error {
+		if source == nil {
+			return errors.New("nonce source must not be nil")
+		}
+		p.nonceSource = source
+		return nil
+	}
+}
+
+// WithWithdrawalPrecision sets the most decimal places allowed in a withdrawal amount per currency,
+// Withdraw rejects amounts with more before signing. The map is copied, later changes to it have no effect.
+func WithWithdrawalPrecision(decimals map[string]int) Option {
+	return func(p *Poloniex) error {
+		copied := make(map[string]int, len(decimals))
+		for currency, d := range decimals {
+			if d < 0 || d > AmountDecimals {
+				return errors.Errorf("withdrawal precision for %s must be between 0 and %d", currency, AmountDecimals)
+			}
+			copied[currency] = d
+		}
+		p.withdrawalDecimals = copied
+		return nil
+	}
+}
+
+// WithWithdrawalAllowlist limits withdrawals to the listed addresses per currency,
+// Withdraw rejects any other destination with ErrAddressNotAllowed before signing.
+// The map and its lists are copied, later changes to them have no effect.
+func WithWithdrawalAllowlist(addresses map[string][]string) Option {
+	return func(p *Poloniex) error {
+		copied := make(map[string][]string, len(addresses))
+		for currency, list := range addresses {
+			copied[currency] = append([]string(nil), list...)
+		}
+		p.withdrawalAllowlist = copied
+		return nil
+	}
+}
+
+// WithReorderWindow sets how long an orderbook message that arrives early is held waiting for the ones
+// before it, after which the missing messages are taken to be lost and the channel is resynced
+func WithReorderWindow(d time.Duration) Option {
+	return func(p *Poloniex) error {
+		if d < 0 {
+			return errors.New("reorder window must not be negative")
+		}
+		p.reorderWindow = d
+		return nil
+	}
+}
+
+// WithStaleTimeout sets how long the websocket may go without any message before it is reconnected,
+// zero turns the watchdog off
+func WithStaleTimeout(d time.Duration) Option {
+	return func(p *Poloniex) error {
+		if d < 0 {
+			return errors.New("stale timeout must not be negative")
+		}
+		p.staleTimeout = d
+		return nil
+	}
+}
+
+// WithLazyMarkets defers loading the market id lookups until they are first needed
+func WithLazyMarkets() Option {
+	return func(p *Poloniex) error {
+		p.lazyMarkets = true
+		return nil
+	}
+}
+
+func validateURL(uri string, schemes ...string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			if u.Host == "" {
+				return errors.New("missing host in " + uri)
+			}
+			return nil
+		}
+	}
+	return errors.New("unsupported scheme in " + uri + ", expected one of " + strings.Join(schemes, ", "))
+}
@@ -0,0 +1,281 @@
+package poloniex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SideBuy is a buy order
+	SideBuy Side = "buy"
+	// SideSell is a sell order
+	SideSell Side = "sell"
+)
+
+const (
+	// TimeInForceGTC leaves the order on the book until it fills or is cancelled
+	TimeInForceGTC TimeInForce = iota
+	// TimeInForcePostOnly only places the order if it would not fill immediately
+	TimeInForcePostOnly
+	// TimeInForceFOK fills the whole order immediately or cancels it
+	TimeInForceFOK
+	// TimeInForceIOC fills as much as possible immediately and cancels the rest
+	TimeInForceIOC
+)
+
+type (
+	// Side is the side of the book an order is placed on
+	Side string
+
+	// TimeInForce decides how long an order stays on the book
+	TimeInForce int
+
+	// OrderRequest describes an order to place with PlaceOrder
+	OrderRequest struct {
+		Side        Side
+		Pair        string
+		Rate        Amount
+		Amount      Amount
+		TimeInForce TimeInForce
+		// ClientOrderID is an optional id of your choosing, unique among your open orders
+		ClientOrderID int64
+	}
+
+	// MoveRequest describes a change to an open order for MoveOrder, a zero Amount keeps the current amount
+	MoveRequest struct {
+		OrderNumber       int64
+		Rate              Amount
+		Amount            Amount
+		PostOnly          bool
+		ImmediateOrCancel bool
+		ClientOrderID     int64
+	}
+)
+
+// Validate reports an error unless s is SideBuy or SideSell
+func (s Side) Validate() error {
+	if s != SideBuy && s != SideSell {
+		return errors.Errorf("invalid order side %q", string(s))
+	}
+	return nil
+}
+
+// UnmarshalJSON lower cases the side the exchange sends, anything else is kept as it is, see Validate
+func (s *Side) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = Side(strings.ToLower(v))
+	return nil
+}
+
+func (t TimeInForce) String() string {
+	switch t {
+	case TimeInForceGTC:
+		return "GTC"
+	case TimeInForcePostOnly:
+		return "PostOnly"
+	case TimeInForceFOK:
+		return "FOK"
+	case TimeInForceIOC:
+		return "IOC"
+	}
+	return fmt.Sprintf("TimeInForce(%d)", int(t))
+}
+
+// Validate checks the request before anything is sent
+func (r OrderRequest) Validate() error {
+	if err := r.Side.Validate(); err != nil {
+		return err
+	}
+	if r.Pair == "" {
+		return errors.New("order pair is required")
+	}
+	if r.Rate.Sign() <= 0 {
+		return errors.Errorf("order rate must be positive, got %s", r.Rate)
+	}
+	if r.Amount.Sign() <= 0 {
+		return errors.Errorf("order amount must be positive, got %s", r.Amount)
+	}
+	if r.TimeInForce < TimeInForceGTC || r.TimeInForce > TimeInForceIOC {
+		return errors.Errorf("invalid time in force %s", r.TimeInForce)
+	}
+	if r.ClientOrderID < 0 {
+		return errors.New("client order id must not be negative")
+	}
+	return nil
+}
+
+func (r OrderRequest) params() url.Values {
+	params := url.Values{}
+	params.Add("currencyPair", r.Pair)
+	params.Add("rate", r.Rate.String())
+	params.Add("amount", r.Amount.String())
+	switch r.TimeInForce {
+	case TimeInForcePostOnly:
+		params.Add("postOnly", "1")
+	case TimeInForceFOK:
+		params.Add("fillOrKill", "1")
+	case TimeInForceIOC:
+		params.Add("immediateOrCancel", "1")
+	}
+	if r.ClientOrderID != 0 {
+		params.Add("clientOrderId", fmt.Sprintf("%d", r.ClientOrderID))
+	}
+	return params
+}
+
+// Validate checks the request before anything is sent
+func (r MoveRequest) Validate() error {
+	if r.OrderNumber <= 0 {
+		return errors.New("order number is required")
+	}
+	if r.Rate.Sign() <= 0 {
+		return errors.Errorf("order rate must be positive, got %s", r.Rate)
+	}
+	if r.Amount.Sign() < 0 {
+		return errors.Errorf("order amount must not be negative, got %s", r.Amount)
+	}
+	if r.PostOnly && r.ImmediateOrCancel {
+		return errors.New("an order cannot be both post only and immediate or cancel")
+	}
+	if r.ClientOrderID < 0 {
+		return errors.New("client order id must not be negative")
+	}
+	return nil
+}
+
+func (r MoveRequest) params() url.Values {
+	params := url.Values{}
+	params.Add("orderNumber", fmt.Sprintf("%d", r.OrderNumber))
+	params.Add("rate", r.Rate.String())
+	if !r.Amount.IsZero() {
+		params.Add("amount", r.Amount.String())
+	}
+	if r.PostOnly {
+		params.Add("postOnly", "1")
+	}
+	if r.ImmediateOrCancel {
+		params.Add("immediateOrCancel", "1")
+	}
+	if r.ClientOrderID != 0 {
+		params.Add("clientOrderId", fmt.Sprintf("%d", r.ClientOrderID))
+	}
+	return params
+}
+
+// PlaceOrder validates and places a buy or sell order
+func (p *Poloniex) PlaceOrder(req OrderRequest) (order Buy, err error) {
+	return p.PlaceOrderContext(context.Background(), req)
+}
+
+// PlaceOrderContext is PlaceOrder with a context for cancellation and deadlines
+func (p *Poloniex) PlaceOrderContext(ctx context.Context, req OrderRequest) (order Buy, err error) {
+	if err = req.Validate(); err != nil {
+		return
+	}
+	err = p.private(ctx, string(req.Side), req.params(), &order)
+	return
+}
+
+// MoveOrder validates and moves an open order to a new rate, optionally changing its amount
+func (p *Poloniex) MoveOrder(req MoveRequest) (moveOrder MoveOrder, err error) {
+	return p.MoveOrderContext(context.Background(), req)
+}
+
+// MoveOrderContext is MoveOrder with a context for cancellation and deadlines
+func (p *Poloniex) MoveOrderContext(ctx context.Context, req MoveRequest) (moveOrder MoveOrder, err error) {
+	if err = req.Validate(); err != nil {
+		return
+	}
+	err = p.private(ctx, "moveOrder", req.params(), &moveOrder)
+	return
+}
+
+func (rt *ResultingTrade) UnmarshalJSON(b []byte) error {
+	// tradeID arrives as a string from some commands and a number from others
+	type resultingTrade ResultingTrade
+	raw := struct {
+		*resultingTrade
+		Date    string
+		TradeID json.Number `json:"tradeID"`
+	}{resultingTrade: (*resultingTrade)(rt)}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw.TradeID != "" {
+		id, err := raw.TradeID.Int64()
+		if err != nil {
+			return errors.Wrap(err, "parsing tradeID failed")
+		}
+		rt.TradeID = id
+	}
+	t, err := parseDate(raw.Date)
+	rt.Date = t
+	return err
+}
+
+// FilledAmount is the total amount filled by the trades
+func (rts ResultingTrades) FilledAmount() (filled Amount) {
+	for _, rt := range rts {
+		filled = filled.Add(rt.Amount)
+	}
+	return
+}
+
+// AveragePrice is the volume weighted average rate of the trades, zero if there are none
+func (rts ResultingTrades) AveragePrice() Amount {
+	var total Amount
+	for _, rt := range rts {
+		total = total.Add(rt.Total)
+	}
+	return total.Div(rts.FilledAmount())
+}
+
+// FilledAmount is the amount filled immediately when the order was placed
+func (b Buy) FilledAmount() Amount {
+	return b.ResultingTrades.FilledAmount()
+}
+
+// AveragePrice is the average rate of the immediate fills, zero if there were none
+func (b Buy) AveragePrice() Amount {
+	return b.ResultingTrades.AveragePrice()
+}
+
+// RemainingAmount is how much of the order is left on the book, or was cancelled for fill or kill and immediate or cancel orders.
+// It is AmountUnfilled when the exchange sends it, otherwise ordered less the amount filled.
+func (b Buy) RemainingAmount(ordered Amount) Amount {
+	if b.AmountUnfilled != nil {
+		return *b.AmountUnfilled
+	}
+	return ordered.Sub(b.FilledAmount())
+}
+
+// Trades returns the fills for every pair in one list
+func (m MoveOrder) Trades() (trades ResultingTrades) {
+	for _, rts := range m.ResultingTrades {
+		trades = append(trades, rts...)
+	}
+	return
+}
+
+// FilledAmount is the amount filled immediately when the order was moved
+func (m MoveOrder) FilledAmount() Amount {
+	return m.Trades().FilledAmount()
+}
+
+// AveragePrice is the average rate of the immediate fills, zero if there were none
+func (m MoveOrder) AveragePrice() Amount {
+	return m.Trades().AveragePrice()
+}
+
+// RemainingAmount is how much of ordered is left on the book after the move
+func (m MoveOrder) RemainingAmount(ordered Amount) Amount {
+	return ordered.Sub(m.FilledAmount())
+}
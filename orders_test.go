@@ -0,0 +1,141 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPlaceOrder(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{
+		"buy":       `{"orderNumber":"31226040"}`,
+		"sell":      `{"orderNumber":"31226041"}`,
+		"moveOrder": `{"success":1,"orderNumber":"31226042"}`,
+	})
+
+	order, err := p.PlaceOrder(OrderRequest{Side: SideBuy, Pair: "BTC_ETH", Rate: MustParseAmount("0.031"), Amount: MustParseAmount("2"), TimeInForce: TimeInForceIOC, ClientOrderID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.OrderNumber != 31226040 {
+		t.Fatalf("unexpected order %+v", order)
+	}
+	sent := (*calls)[0]
+	if sent.Get("command") != "buy" || sent.Get("immediateOrCancel") != "1" || sent.Get("clientOrderId") != "42" || sent.Get("rate") != "0.03100000" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	if _, err := p.SellPostOnly("BTC_ETH", 0.032, 1); err != nil {
+		t.Fatal(err)
+	}
+	if sent := (*calls)[1]; sent.Get("command") != "sell" || sent.Get("postOnly") != "1" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	if _, err := p.MovePostOnly(31226041, 0.033); err != nil {
+		t.Fatal(err)
+	}
+	if sent := (*calls)[2]; sent.Get("postOnly") != "1" || sent.Get("amount") != "" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	move, err := p.MoveOrder(MoveRequest{OrderNumber: 31226041, Rate: MustParseAmount("0.033"), Amount: MustParseAmount("0.5"), ImmediateOrCancel: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.OrderNumber != 31226042 {
+		t.Fatalf("unexpected move %+v", move)
+	}
+	if sent := (*calls)[3]; sent.Get("amount") != "0.50000000" || sent.Get("immediateOrCancel") != "1" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	// floats too large for an Amount are rejected before anything is sent
+	if _, err := p.Buy("BTC_ETH", 1e20, 1); err == nil {
+		t.Error("expected an out of range rate to be rejected")
+	}
+	if _, err := p.Sell("BTC_ETH", 0.03, math.NaN()); err == nil {
+		t.Error("expected a NaN amount to be rejected")
+	}
+	if _, err := p.Move(31226041, 1e15); err == nil {
+		t.Error("expected an out of range move rate to be rejected")
+	}
+	if _, err := p.MarginBuy("BTC_ETH", 0.03, 1, 1e20); err == nil {
+		t.Error("expected an out of range lending rate to be rejected")
+	}
+	if len(*calls) != 4 {
+		t.Fatalf("out of range orders were sent, %d calls", len(*calls))
+	}
+}
+
+func TestOrderValidation(t *testing.T) {
+	valid := OrderRequest{Side: SideSell, Pair: "BTC_ETH", Rate: 1, Amount: 1}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []OrderRequest{
+		{Side: "short", Pair: "BTC_ETH", Rate: 1, Amount: 1},
+		{Side: SideBuy, Rate: 1, Amount: 1},
+		{Side: SideBuy, Pair: "BTC_ETH", Amount: 1},
+		{Side: SideBuy, Pair: "BTC_ETH", Rate: 1, Amount: -1},
+		{Side: SideBuy, Pair: "BTC_ETH", Rate: 1, Amount: 1, TimeInForce: 7},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", r)
+		}
+	}
+	if err := (MoveRequest{OrderNumber: 1, Rate: 1, PostOnly: true, ImmediateOrCancel: true}).Validate(); err == nil {
+		t.Error("expected post only with immediate or cancel to be invalid")
+	}
+}
+
+func TestResultingTrades(t *testing.T) {
+	p, _ := newTestClient(t, map[string]string{
+		"buy": `{"orderNumber":"31226040","resultingTrades":[
+			{"amount":"338.87320000","date":"2014-10-18 23:03:21","rate":"0.00000173","total":"0.00058625","tradeID":"16164","type":"buy","fee":"0.00200000"},
+			{"amount":"100.00000000","date":"2014-10-18 23:03:21","rate":"0.00000175","total":"0.00017500","tradeID":16165,"type":"buy"}]}`,
+		"moveOrder": `{"success":1,"orderNumber":"239574176","resultingTrades":{"BTC_BTS":[
+			{"amount":"1.00000000","date":"2014-10-18 23:03:22","rate":"0.00000200","total":"0.00000200","tradeID":"16166","type":"sell"}]}}`,
+	})
+
+	buy, err := p.Buy("BTC_BTS", 0.00000175, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buy.ResultingTrades) != 2 {
+		t.Fatalf("unexpected trades %+v", buy.ResultingTrades)
+	}
+	first := buy.ResultingTrades[0]
+	if first.TradeID != 16164 || buy.ResultingTrades[1].TradeID != 16165 || first.Fee != MustParseAmount("0.002") {
+		t.Fatalf("unexpected trade %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2014, 10, 18, 23, 3, 21, 0, time.UTC)) {
+		t.Fatalf("unexpected date %s", first.Date)
+	}
+	if f := buy.FilledAmount(); f != MustParseAmount("438.8732") {
+		t.Fatalf("unexpected filled amount %s", f)
+	}
+	if a := buy.AveragePrice(); a != MustParseAmount("0.00000173") {
+		t.Fatalf("unexpected average price %s", a)
+	}
+	if r := buy.RemainingAmount(MustParseAmount("500")); r != MustParseAmount("61.1268") {
+		t.Fatalf("unexpected remaining amount %s", r)
+	}
+
+	var ioc Buy
+	if err := json.Unmarshal([]byte(`{"orderNumber":"31226041","resultingTrades":[],"amountUnfilled":"0.00000000"}`), &ioc); err != nil {
+		t.Fatal(err)
+	}
+	if r := ioc.RemainingAmount(MustParseAmount("500")); !r.IsZero() {
+		t.Fatalf("unexpected remaining amount %s, want the amountUnfilled sent", r)
+	}
+
+	move, err := p.Move(31226040, 0.000002)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.FilledAmount() != MustParseAmount("1") || move.Trades()[0].TradeID != 16166 {
+		t.Fatalf("unexpected move %+v", move)
+	}
+}
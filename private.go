@@ -1,18 +1,20 @@
 package poloniex
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
+	"net/http"
+	"net/http/httptrace"
 	"net/url"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/bitly/go-simplejson"
-	"github.com/franela/goreq"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -27,9 +29,9 @@ type (
 	Balances map[string]Balance
 	//Balance is a single balance entry used in the Balances map
 	Balance struct {
-		Available float64 `json:",string"`
-		OnOrders  float64 `json:"onOrders,string"`
-		BTCValue  float64 `json:"btcValue,string"`
+		Available Amount
+		OnOrders  Amount `json:"onOrders"`
+		BTCValue  Amount `json:"btcValue"`
 	}
 
 	accountBalancesTemp struct {
@@ -40,9 +42,9 @@ type (
 
 	//Account holds the balances in the various wallet accounts
 	AccountBalances struct {
-		Exchange map[string]float64
-		Margin   map[string]float64
-		Lending  map[string]float64
+		Exchange map[string]Amount
+		Margin   map[string]Amount
+		Lending  map[string]Amount
 	}
 
 	//Addresses holds the various deposit addresses foreach coin
@@ -50,25 +52,8 @@ type (
 
 	//DepositsWithdrawals holds the history of deposit and withdrawal
 	DepositsWithdrawals struct {
-		Deposits    []deposit
-		Withdrawals []withdrawal
-	}
-	deposit struct {
-		Currency      string
-		Address       string
-		Amount        float64 `json:",string"`
-		Confirmations int64
-		TXID          string `json:"txid"`
-		Timestamp     int64
-		Status        string
-	}
-	withdrawal struct {
-		WithdrawalNumber int64 `json:"withdrawalNumber"`
-		Currency         string
-		Address          string
-		Amount           float64 `json:",string"`
-		Timestamp        int64
-		Status           string
+		Deposits    []Deposit
+		Withdrawals []Withdrawal
 	}
 
 	//OpenOrders is the list of open orders for the pair specified
@@ -76,61 +61,88 @@ type (
 	//OpenOrder is a singular entry used in the OpenOrders type
 	OpenOrder struct {
 		OrderNumber int64 `json:",string"`
-		Type        string
-		Rate        float64 `json:",string"`
-		Amount      float64 `json:",string"`
-		Total       float64 `json:",string"`
+		Type        Side
+		Rate        Amount
+		Amount      Amount
+		Total       Amount
 	}
 	//OpenOrdersAll is used for all pairs
 	OpenOrdersAll map[string]OpenOrders
 
-	PrivateTradeHistory      []PrivateTradeHistoryEntry
+	PrivateTradeHistory []PrivateTradeHistoryEntry
+	//PrivateTradeHistoryEntry is one of your trades, Date is in UTC
 	PrivateTradeHistoryEntry struct {
-		Date          string
-		Rate          float64 `json:",string"`
-		Amount        float64 `json:",string"`
-		Total         float64 `json:",string"`
-		OrderNumber   int64   `json:",string"`
-		Type          string
+		Date          time.Time
+		Rate          Amount
+		Amount        Amount
+		Total         Amount
+		Fee           Amount
+		OrderNumber   int64 `json:",string"`
+		Type          Side
+		Category      OrderType
 		GlobalTradeID int64 `json:"globalTradeID"`
 	}
 	PrivateTradeHistoryAll map[string]PrivateTradeHistory
 
 	OrderTrades []OrderTrade
 	OrderTrade  struct {
-		GlobalTradeID int64   `json:"globalTradeID"`
-		TradeID       int64   `json:"tradeID"`
-		CurrencyPair  string  `json:"currencyPair"`
-		Type          string  `json:"type"`
-		Rate          float64 `json:"rate,string"`
-		Amount        float64 `json:"amount,string"`
-		Total         float64 `json:"total,string"`
-		Fee           float64 `json:"fee,string"`
-		Date          string  `json:"date"`
-	}
-
+		GlobalTradeID int64     `json:"globalTradeID"`
+		TradeID       int64     `json:"tradeID"`
+		CurrencyPair  string    `json:"currencyPair"`
+		Type          Side      `json:"type"`
+		Rate          Amount    `json:"rate"`
+		Amount        Amount    `json:"amount"`
+		Total         Amount    `json:"total"`
+		Fee           Amount    `json:"fee"`
+		Date          time.Time `json:"date"`
+	}
+
+	//CancelAllOrders lists the orders cancelled by CancelAllOrders
+	CancelAllOrders struct {
+		Success      int64
+		Message      string
+		OrderNumbers []int64 `json:"orderNumbers"`
+	}
+
+	//Buy is the result of placing an order, ResultingTrades holds any immediate fills.
+	//AmountUnfilled is nil unless the exchange sends it, as it does for fill or kill and immediate or cancel orders.
 	Buy struct {
-		OrderNumber int64 `json:",string"`
-		// ResultingTrades []ResultingTrade
+		OrderNumber     int64 `json:",string"`
+		ResultingTrades ResultingTrades
+		AmountUnfilled  *Amount `json:"amountUnfilled"`
 	}
+	//ResultingTrades are the fills of an order placed or moved
+	ResultingTrades []ResultingTrade
+	//ResultingTrade is a single fill, Date is in UTC
 	ResultingTrade struct {
-		Amount  float64 `json:",string"`
-		Rate    float64 `json:",string"`
-		Date    string
-		Total   float64 `json:",string"`
-		TradeID string  `json:"tradeID"`
-		Type    string
+		Amount          Amount
+		Rate            Amount
+		Date            time.Time
+		Total           Amount
+		Fee             Amount
+		TakerAdjustment Amount `json:"takerAdjustment"`
+		TradeID         int64  `json:"tradeID"`
+		Type            Side
 	}
 	Sell struct {
 		Buy
 	}
 
+	//MoveOrder is the result of moving an order, ResultingTrades is keyed by pair
 	MoveOrder struct {
 		Base
-		OrderNumber int64 `json:",string"`
-		// ResultingTrades []ResultingTrade
+		OrderNumber     int64 `json:",string"`
+		ResultingTrades map[string]ResultingTrades
 	}
 
+	//WithdrawRequest describes a withdrawal, PaymentID is the payment id, memo or tag some currencies need
+	WithdrawRequest struct {
+		Currency  string
+		Amount    Amount
+		Address   string
+		PaymentID string
+	}
+	//Withdraw is the result of a withdrawal, Response holds the exchange's message
 	Withdraw struct {
 		Base
 	}
@@ -138,14 +150,14 @@ type (
 	FeeInfo struct {
 		MakerFee        float64 `json:"makerFee,string"`
 		TakerFee        float64 `json:"takerFee,string"`
-		ThirtyDayVolume float64 `json:"thirtyDayVolume,string"`
-		NextTier        float64 `json:"nextTier,string"`
+		ThirtyDayVolume Amount  `json:"thirtyDayVolume"`
+		NextTier        Amount  `json:"nextTier"`
 	}
 
 	AvailableAccountBalances struct {
-		Exchange map[string]float64
-		Margin   map[string]float64
-		Lending  map[string]float64
+		Exchange map[string]Amount
+		Margin   map[string]Amount
+		Lending  map[string]Amount
 	}
 	AvailableAccountBalancesTemp struct {
 		Exchange map[string]json.Number
@@ -154,7 +166,7 @@ type (
 	}
 
 	TradableBalances map[string]TradableBalance
-	TradableBalance  map[string]float64
+	TradableBalance  map[string]Amount
 
 	TradableBalancesTemp map[string]TradableBalanceTemp
 	TradableBalanceTemp  map[string]json.Number
@@ -165,14 +177,42 @@ type (
 	}
 
 	MarginAccountSummary struct {
-		TotalValue         float64 `json:"totalValue,string"`
-		ProfitLoss         float64 `json:"pl,string"`
-		LendingFees        float64 `json:"lendingFees,string"`
-		NetValue           float64 `json:"netValue,string"`
-		TotalBorrowedValue float64 `json:"totalBorrowedValue,string"`
+		TotalValue         Amount  `json:"totalValue"`
+		ProfitLoss         Amount  `json:"pl"`
+		LendingFees        Amount  `json:"lendingFees"`
+		NetValue           Amount  `json:"netValue"`
+		TotalBorrowedValue Amount  `json:"totalBorrowedValue"`
 		CurrentMargin      float64 `json:"currentMargin,string"`
 	}
 
+	// MarginOrder is the result of a MarginBuy or MarginSell, ResultingTrades is keyed by pair
+	MarginOrder struct {
+		Success         int64
+		Message         string
+		OrderNumber     int64 `json:",string"`
+		ResultingTrades map[string]ResultingTrades
+	}
+
+	// MarginPosition describes the open margin position for a pair, Type is "long", "short" or "none"
+	MarginPosition struct {
+		Amount           Amount
+		Total            Amount
+		BasePrice        Amount `json:"basePrice"`
+		LiquidationPrice Amount `json:"liquidationPrice"`
+		ProfitLoss       Amount `json:"pl"`
+		LendingFees      Amount `json:"lendingFees"`
+		Type             string
+	}
+	// MarginPositions holds the margin position for every pair
+	MarginPositions map[string]MarginPosition
+
+	// CloseMarginPosition is the result of closing a margin position, ResultingTrades is keyed by pair
+	CloseMarginPosition struct {
+		Success         int64
+		Message         string
+		ResultingTrades map[string]ResultingTrades
+	}
+
 	LoanOffer struct {
 		Base
 		OrderID int64 `json:"orderID"`
@@ -180,92 +220,162 @@ type (
 
 	OpenLoanOffers map[string][]OpenLoanOffer
 	OpenLoanOffer  struct {
-		ID        int64   `json:"id"`
-		Rate      float64 `json:",string"`
-		Amount    float64 `json:",string"`
+		ID        int64 `json:"id"`
+		Rate      Amount
+		Amount    Amount
 		Duration  int64
 		Renewable bool
 		AutoRenew int64 `json:"autoRenew"`
-		Date      string
+		Date      time.Time
+		// Deprecated: DateTaken is the same as Date
 		DateTaken time.Time
 	}
 
+	//ActiveLoans holds the loans provided to and used by the account
 	ActiveLoans struct {
 		Provided []ActiveLoan
+		Used     []ActiveLoan
 	}
 	ActiveLoan struct {
 		ID        int64 `json:"id"`
 		Currency  string
-		Rate      float64 `json:",string"`
-		Amount    float64 `json:",string"`
+		Rate      Amount
+		Amount    Amount
 		Range     int64
 		Renewable bool
 		AutoRenew int64 `json:"autoRenew"`
-		Date      string
+		Date      time.Time
+		// Deprecated: DateTaken is the same as Date
 		DateTaken time.Time
-		Fees      float64 `json:",string"`
+		Fees      Amount
+	}
+
+	//LendingHistory is the list of closed loans returned by LendingHistory
+	LendingHistory      []LendingHistoryEntry
+	LendingHistoryEntry struct {
+		ID       int64 `json:"id"`
+		Currency string
+		Rate     Amount
+		Amount   Amount
+		Duration float64 `json:",string"`
+		Interest Amount
+		Fee      Amount
+		Earned   Amount
+		Open     time.Time
+		Close    time.Time
 	}
 )
 
+// ErrAddressNotAllowed is returned by Withdraw when the destination is not in the allowlist set with WithWithdrawalAllowlist
+var ErrAddressNotAllowed = errors.New("poloniex: withdrawal address not in allowlist")
+
+// poloniexDateFormat is the layout of the date strings sent by the REST API, all in UTC
+const poloniexDateFormat = "2006-01-02 15:04:05"
+
 func (p *Poloniex) Balances() (balances Balances, err error) {
-	err = p.private("returnCompleteBalances", nil, &balances)
+	return p.BalancesContext(context.Background())
+}
+
+// BalancesContext is Balances with a context for cancellation and deadlines
+func (p *Poloniex) BalancesContext(ctx context.Context) (balances Balances, err error) {
+	err = p.private(ctx, "returnCompleteBalances", nil, &balances)
 	return balances, err
 }
 
 func (p *Poloniex) AccountBalances() (balances AccountBalances, err error) {
+	return p.AccountBalancesContext(context.Background())
+}
+
+// AccountBalancesContext is AccountBalances with a context for cancellation and deadlines
+func (p *Poloniex) AccountBalancesContext(ctx context.Context) (balances AccountBalances, err error) {
 	b := accountBalancesTemp{}
-	p.private("returnAvailableAccountBalances", nil, &b)
-	balances = AccountBalances{Exchange: map[string]float64{}, Margin: map[string]float64{}, Lending: map[string]float64{}}
+	if err = p.private(ctx, "returnAvailableAccountBalances", nil, &b); err != nil {
+		return
+	}
+	balances = AccountBalances{Exchange: map[string]Amount{}, Margin: map[string]Amount{}, Lending: map[string]Amount{}}
 	for k, v := range b.Exchange {
-		balances.Exchange[k] = toFloat(v)
+		if balances.Exchange[k], err = toAmount(v); err != nil {
+			return
+		}
 	}
 	for k, v := range b.Margin {
-		balances.Margin[k] = toFloat(v)
+		if balances.Margin[k], err = toAmount(v); err != nil {
+			return
+		}
 	}
 	for k, v := range b.Lending {
-		balances.Lending[k] = toFloat(v)
+		if balances.Lending[k], err = toAmount(v); err != nil {
+			return
+		}
 	}
 	return
 }
 
 func (p *Poloniex) Addresses() (addresses Addresses, err error) {
-	p.private("returnDepositAddresses", nil, &addresses)
+	return p.AddressesContext(context.Background())
+}
+
+// AddressesContext is Addresses with a context for cancellation and deadlines
+func (p *Poloniex) AddressesContext(ctx context.Context) (addresses Addresses, err error) {
+	err = p.private(ctx, "returnDepositAddresses", nil, &addresses)
 	return
 }
 
 func (p *Poloniex) GenerateNewAddress(currency string) (address string, err error) {
+	return p.GenerateNewAddressContext(context.Background(), currency)
+}
+
+// GenerateNewAddressContext is GenerateNewAddress with a context for cancellation and deadlines
+func (p *Poloniex) GenerateNewAddressContext(ctx context.Context, currency string) (address string, err error) {
 	params := url.Values{}
 	params.Add("currency", currency)
 	b := Base{}
-	err = p.private("generateNewAddress", params, &b)
+	err = p.private(ctx, "generateNewAddress", params, &b)
 	address = b.Response
 	return
 }
 
+//DepositsWithdrawals returns deposits and withdrawals made in roughly the last 7 months, see DepositsWithdrawalsRange for other periods
 func (p *Poloniex) DepositsWithdrawals() (depositsWithdrawals DepositsWithdrawals, err error) {
-	params := url.Values{}
-	params.Add("start", fmt.Sprintf("%d", time.Now().Add(-5208*time.Hour).Unix()))
-	params.Add("end", "9999999999")
-	err = p.private("returnDepositsWithdrawals", params, &depositsWithdrawals)
-	return
+	return p.DepositsWithdrawalsContext(context.Background())
+}
+
+// DepositsWithdrawalsContext is DepositsWithdrawals with a context for cancellation and deadlines
+func (p *Poloniex) DepositsWithdrawalsContext(ctx context.Context) (depositsWithdrawals DepositsWithdrawals, err error) {
+	return p.DepositsWithdrawalsRangeContext(ctx, time.Now().Add(-5208*time.Hour), time.Unix(9999999999, 0))
 }
 
 func (p *Poloniex) OpenOrders(pair string) (openOrders OpenOrders, err error) {
+	return p.OpenOrdersContext(context.Background(), pair)
+}
+
+// OpenOrdersContext is OpenOrders with a context for cancellation and deadlines
+func (p *Poloniex) OpenOrdersContext(ctx context.Context, pair string) (openOrders OpenOrders, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", pair)
-	err = p.private("returnOpenOrders", params, &openOrders)
+	err = p.private(ctx, "returnOpenOrders", params, &openOrders)
 	return
 }
 
 func (p *Poloniex) OpenOrdersAll() (openOrders OpenOrdersAll, err error) {
+	return p.OpenOrdersAllContext(context.Background())
+}
+
+// OpenOrdersAllContext is OpenOrdersAll with a context for cancellation and deadlines
+func (p *Poloniex) OpenOrdersAllContext(ctx context.Context) (openOrders OpenOrdersAll, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", "all")
-	err = p.private("returnOpenOrders", params, &openOrders)
+	err = p.private(ctx, "returnOpenOrders", params, &openOrders)
 	return
 }
 
 //PrivateTradeHistory takes a string pair and 2 unix timestamps as the start and end date period for the request.
 func (p *Poloniex) PrivateTradeHistory(pair string, dates ...int64) (history PrivateTradeHistory, err error) {
+	return p.PrivateTradeHistoryContext(context.Background(), pair, dates...)
+}
+
+// PrivateTradeHistoryContext is PrivateTradeHistory with a context for cancellation and deadlines
+func (p *Poloniex) PrivateTradeHistoryContext(ctx context.Context, pair string, dates ...int64) (history PrivateTradeHistory, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", pair)
 	if len(dates) > 0 {
@@ -276,12 +386,17 @@ func (p *Poloniex) PrivateTradeHistory(pair string, dates ...int64) (history Pri
 		// we have an end date
 		params.Add("end", fmt.Sprintf("%d", dates[1]))
 	}
-	err = p.private("returnTradeHistory", params, &history)
+	err = p.private(ctx, "returnTradeHistory", params, &history)
 	return
 }
 
 //PrivateTradeHistoryAll takes 2 unix timestamps as the start and end date period for the request.
 func (p *Poloniex) PrivateTradeHistoryAll(dates ...int64) (history PrivateTradeHistoryAll, err error) {
+	return p.PrivateTradeHistoryAllContext(context.Background(), dates...)
+}
+
+// PrivateTradeHistoryAllContext is PrivateTradeHistoryAll with a context for cancellation and deadlines
+func (p *Poloniex) PrivateTradeHistoryAllContext(ctx context.Context, dates ...int64) (history PrivateTradeHistoryAll, err error) {
 	params := url.Values{}
 	if len(dates) > 0 {
 		// we have a start date
@@ -292,138 +407,278 @@ func (p *Poloniex) PrivateTradeHistoryAll(dates ...int64) (history PrivateTradeH
 		params.Add("end", fmt.Sprintf("%d", dates[1]))
 	}
 	params.Add("currencyPair", "all")
-	err = p.private("returnTradeHistory", params, &history)
+	err = p.private(ctx, "returnTradeHistory", params, &history)
 	return
 }
 
 func (p *Poloniex) OrderTrades(orderNumber int64) (ot OrderTrades, err error) {
+	return p.OrderTradesContext(context.Background(), orderNumber)
+}
+
+// OrderTradesContext is OrderTrades with a context for cancellation and deadlines
+func (p *Poloniex) OrderTradesContext(ctx context.Context, orderNumber int64) (ot OrderTrades, err error) {
 	params := url.Values{}
 	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
-	err = p.private("returnOrderTrades", params, &ot)
+	err = p.private(ctx, "returnOrderTrades", params, &ot)
 	return
 }
 
 func (p *Poloniex) CancelOrder(orderNumber int64) (success bool, err error) {
+	return p.CancelOrderContext(context.Background(), orderNumber)
+}
+
+// CancelOrderContext is CancelOrder with a context for cancellation and deadlines
+func (p *Poloniex) CancelOrderContext(ctx context.Context, orderNumber int64) (success bool, err error) {
 	params := url.Values{}
 	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
 	b := Base{}
-	err = p.private("cancelOrder", params, &b)
+	err = p.private(ctx, "cancelOrder", params, &b)
 	success = b.Success == 1
 	return
 }
 
-func (p *Poloniex) Buy(pair string, rate, amount float64) (buy Buy, err error) {
+//CancelAllOrders cancels every open order for pair
+func (p *Poloniex) CancelAllOrders(pair string) (cancelled CancelAllOrders, err error) {
+	return p.CancelAllOrdersContext(context.Background(), pair)
+}
+
+// CancelAllOrdersContext is CancelAllOrders with a context for cancellation and deadlines
+func (p *Poloniex) CancelAllOrdersContext(ctx context.Context, pair string) (cancelled CancelAllOrders, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", pair)
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	params.Add("amount", fmt.Sprintf("%.8f", amount))
-	err = p.private("buy", params, &buy)
+	err = p.private(ctx, "cancelAllOrders", params, &cancelled)
 	return
 }
 
-func (p *Poloniex) BuyPostOnly(pair string, rate, amount float64) (buy Buy, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	params.Add("amount", fmt.Sprintf("%.8f", amount))
-	params.Add("postOnly", "1")
-	err = p.private("buy", params, &buy)
+//CancelAllOrdersAll cancels every open order in every market
+func (p *Poloniex) CancelAllOrdersAll() (cancelled CancelAllOrders, err error) {
+	return p.CancelAllOrdersAllContext(context.Background())
+}
+
+// CancelAllOrdersAllContext is CancelAllOrdersAll with a context for cancellation and deadlines
+func (p *Poloniex) CancelAllOrdersAllContext(ctx context.Context) (cancelled CancelAllOrders, err error) {
+	err = p.private(ctx, "cancelAllOrders", nil, &cancelled)
 	return
 }
 
+func (p *Poloniex) Buy(pair string, rate, amount float64) (buy Buy, err error) {
+	return p.BuyContext(context.Background(), pair, rate, amount)
+}
+
+// BuyContext is Buy with a context for cancellation and deadlines
+func (p *Poloniex) BuyContext(ctx context.Context, pair string, rate, amount float64) (buy Buy, err error) {
+	req, err := floatOrderRequest(SideBuy, pair, rate, amount, TimeInForceGTC)
+	if err != nil {
+		return
+	}
+	return p.PlaceOrderContext(ctx, req)
+}
+
+func (p *Poloniex) BuyPostOnly(pair string, rate, amount float64) (buy Buy, err error) {
+	return p.BuyPostOnlyContext(context.Background(), pair, rate, amount)
+}
+
+// BuyPostOnlyContext is BuyPostOnly with a context for cancellation and deadlines
+func (p *Poloniex) BuyPostOnlyContext(ctx context.Context, pair string, rate, amount float64) (buy Buy, err error) {
+	req, err := floatOrderRequest(SideBuy, pair, rate, amount, TimeInForcePostOnly)
+	if err != nil {
+		return
+	}
+	return p.PlaceOrderContext(ctx, req)
+}
+
 func (p *Poloniex) BuyFillKill(pair string, rate, amount float64) (buy Buy, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	params.Add("amount", fmt.Sprintf("%.8f", amount))
-	params.Add("fillOrKill", "1")
-	err = p.private("buy", params, &buy)
-	return
+	return p.BuyFillKillContext(context.Background(), pair, rate, amount)
+}
+
+// BuyFillKillContext is BuyFillKill with a context for cancellation and deadlines
+func (p *Poloniex) BuyFillKillContext(ctx context.Context, pair string, rate, amount float64) (buy Buy, err error) {
+	req, err := floatOrderRequest(SideBuy, pair, rate, amount, TimeInForceFOK)
+	if err != nil {
+		return
+	}
+	return p.PlaceOrderContext(ctx, req)
 }
 
 func (p *Poloniex) Sell(pair string, rate, amount float64) (sell Sell, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	params.Add("amount", fmt.Sprintf("%.8f", amount))
-	err = p.private("sell", params, &sell)
+	return p.SellContext(context.Background(), pair, rate, amount)
+}
+
+// SellContext is Sell with a context for cancellation and deadlines
+func (p *Poloniex) SellContext(ctx context.Context, pair string, rate, amount float64) (sell Sell, err error) {
+	req, err := floatOrderRequest(SideSell, pair, rate, amount, TimeInForceGTC)
+	if err != nil {
+		return
+	}
+	sell.Buy, err = p.PlaceOrderContext(ctx, req)
 	return
 }
 
 func (p *Poloniex) SellPostOnly(pair string, rate, amount float64) (sell Sell, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	params.Add("amount", fmt.Sprintf("%.8f", amount))
-	params.Add("postOnly", "1")
-	err = p.private("sell", params, &sell)
+	return p.SellPostOnlyContext(context.Background(), pair, rate, amount)
+}
+
+// SellPostOnlyContext is SellPostOnly with a context for cancellation and deadlines
+func (p *Poloniex) SellPostOnlyContext(ctx context.Context, pair string, rate, amount float64) (sell Sell, err error) {
+	req, err := floatOrderRequest(SideSell, pair, rate, amount, TimeInForcePostOnly)
+	if err != nil {
+		return
+	}
+	sell.Buy, err = p.PlaceOrderContext(ctx, req)
 	return
 }
 
 func (p *Poloniex) SellFillKill(pair string, rate, amount float64) (sell Sell, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	params.Add("amount", fmt.Sprintf("%.8f", amount))
-	params.Add("fillOrKill", "1")
-	err = p.private("sell", params, &sell)
+	return p.SellFillKillContext(context.Background(), pair, rate, amount)
+}
+
+// SellFillKillContext is SellFillKill with a context for cancellation and deadlines
+func (p *Poloniex) SellFillKillContext(ctx context.Context, pair string, rate, amount float64) (sell Sell, err error) {
+	req, err := floatOrderRequest(SideSell, pair, rate, amount, TimeInForceFOK)
+	if err != nil {
+		return
+	}
+	sell.Buy, err = p.PlaceOrderContext(ctx, req)
 	return
 }
 
+// floatOrderRequest builds the request placed by the float64 order calls, rejecting rates and amounts
+// too large for an Amount
+func floatOrderRequest(side Side, pair string, rate, amount float64, tif TimeInForce) (req OrderRequest, err error) {
+	var f amountFields
+	req = OrderRequest{Side: side, Pair: pair, Rate: f.parse(rate), Amount: f.parse(amount), TimeInForce: tif}
+	return req, f.err
+}
+
 func (p *Poloniex) Move(orderNumber int64, rate float64) (moveOrder MoveOrder, err error) {
-	params := url.Values{}
-	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	err = p.private("moveOrder", params, &moveOrder)
-	return
+	return p.MoveContext(context.Background(), orderNumber, rate)
+}
+
+// MoveContext is Move with a context for cancellation and deadlines
+func (p *Poloniex) MoveContext(ctx context.Context, orderNumber int64, rate float64) (moveOrder MoveOrder, err error) {
+	r, err := amountFromFloat(rate)
+	if err != nil {
+		return
+	}
+	return p.MoveOrderContext(ctx, MoveRequest{OrderNumber: orderNumber, Rate: r})
 }
 
 func (p *Poloniex) MovePostOnly(orderNumber int64, rate float64) (moveOrder MoveOrder, err error) {
-	params := url.Values{}
-	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
-	params.Add("rate", fmt.Sprintf("%.8f", rate))
-	err = p.private("moveOrder", params, &moveOrder)
-	return
+	return p.MovePostOnlyContext(context.Background(), orderNumber, rate)
+}
+
+// MovePostOnlyContext is MovePostOnly with a context for cancellation and deadlines
+func (p *Poloniex) MovePostOnlyContext(ctx context.Context, orderNumber int64, rate float64) (moveOrder MoveOrder, err error) {
+	r, err := amountFromFloat(rate)
+	if err != nil {
+		return
+	}
+	return p.MoveOrderContext(ctx, MoveRequest{OrderNumber: orderNumber, Rate: r, PostOnly: true})
 }
 
-func (p *Poloniex) Withdraw(currency string, amount float64, address string) (w Withdraw, err error) {
+//Withdraw validates req, checks it against the address allowlist if one is set and then requests the withdrawal
+func (p *Poloniex) Withdraw(req WithdrawRequest) (w Withdraw, err error) {
+	return p.WithdrawContext(context.Background(), req)
+}
+
+// WithdrawContext is Withdraw with a context for cancellation and deadlines
+func (p *Poloniex) WithdrawContext(ctx context.Context, req WithdrawRequest) (w Withdraw, err error) {
+	if err = p.checkWithdrawal(req); err != nil {
+		return
+	}
 	params := url.Values{}
-	params.Add("currency", currency)
-	params.Add("amount", fmt.Sprintf("%f", amount))
-	params.Add("address", address)
-	err = p.private("withdraw", params, w)
+	params.Add("currency", req.Currency)
+	params.Add("amount", req.Amount.String())
+	params.Add("address", req.Address)
+	if req.PaymentID != "" {
+		params.Add("paymentId", req.PaymentID)
+	}
+	err = p.private(ctx, "withdraw", params, &w)
 	return
 }
 
+// Validate checks the request before anything is sent
+func (r WithdrawRequest) Validate() error {
+	if r.Currency == "" {
+		return errors.New("withdrawal currency is required")
+	}
+	if r.Address == "" {
+		return errors.New("withdrawal address is required")
+	}
+	if r.Amount.Sign() <= 0 {
+		return errors.Errorf("withdrawal amount must be positive, got %s", r.Amount)
+	}
+	return nil
+}
+
+func (p *Poloniex) checkWithdrawal(req WithdrawRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	if decimals, ok := p.withdrawalDecimals[req.Currency]; ok && req.Amount.Decimals() > decimals {
+		return errors.Errorf("withdrawal amount %s has more than %d decimal places allowed for %s", req.Amount, decimals, req.Currency)
+	}
+	if p.withdrawalAllowlist != nil {
+		for _, address := range p.withdrawalAllowlist[req.Currency] {
+			if address == req.Address {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrAddressNotAllowed, "%s address %s", req.Currency, req.Address)
+	}
+	return nil
+}
+
 func (p *Poloniex) FeeInfo() (fi FeeInfo, err error) {
-	err = p.private("returnFeeInfo", nil, &fi)
+	return p.FeeInfoContext(context.Background())
+}
+
+// FeeInfoContext is FeeInfo with a context for cancellation and deadlines
+func (p *Poloniex) FeeInfoContext(ctx context.Context) (fi FeeInfo, err error) {
+	err = p.private(ctx, "returnFeeInfo", nil, &fi)
 	return
 }
 
 func (p *Poloniex) AvailableAccountBalances() (aab AvailableAccountBalances, err error) {
+	return p.AvailableAccountBalancesContext(context.Background())
+}
+
+// AvailableAccountBalancesContext is AvailableAccountBalances with a context for cancellation and deadlines
+func (p *Poloniex) AvailableAccountBalancesContext(ctx context.Context) (aab AvailableAccountBalances, err error) {
 	aabt := AvailableAccountBalancesTemp{}
-	err = p.private("returnAvailableAccountBalances", nil, &aabt)
+	err = p.private(ctx, "returnAvailableAccountBalances", nil, &aabt)
 	if err != nil {
 		return
 	}
-	aab.Exchange = map[string]float64{}
-	aab.Margin = map[string]float64{}
-	aab.Lending = map[string]float64{}
+	aab.Exchange = map[string]Amount{}
+	aab.Margin = map[string]Amount{}
+	aab.Lending = map[string]Amount{}
 	for k, v := range aabt.Exchange {
-		aab.Exchange[k] = toFloat(v)
+		if aab.Exchange[k], err = toAmount(v); err != nil {
+			return
+		}
 	}
 	for k, v := range aabt.Margin {
-		aab.Margin[k] = toFloat(v)
+		if aab.Margin[k], err = toAmount(v); err != nil {
+			return
+		}
 	}
 	for k, v := range aabt.Lending {
-		aab.Lending[k] = toFloat(v)
+		if aab.Lending[k], err = toAmount(v); err != nil {
+			return
+		}
 	}
 	return
 }
 
 func (p *Poloniex) TradableBalances() (tb TradableBalances, err error) {
+	return p.TradableBalancesContext(context.Background())
+}
+
+// TradableBalancesContext is TradableBalances with a context for cancellation and deadlines
+func (p *Poloniex) TradableBalancesContext(ctx context.Context) (tb TradableBalances, err error) {
 	tbt := TradableBalancesTemp{}
-	err = p.private("returnTradableBalances", nil, &tbt)
+	err = p.private(ctx, "returnTradableBalances", nil, &tbt)
 	if err != nil {
 		return
 	}
@@ -431,143 +686,302 @@ func (p *Poloniex) TradableBalances() (tb TradableBalances, err error) {
 	for k, v := range tbt {
 		tb[k] = TradableBalance{}
 		for kk, vv := range v {
-			tb[k][kk] = toFloat(vv)
+			if tb[k][kk], err = toAmount(vv); err != nil {
+				return
+			}
 		}
 	}
 	return
 }
 
-func (p *Poloniex) TransferBalance(currency string, amount float64, from string, to string) (tb TransferBalance, err error) {
+func (p *Poloniex) TransferBalance(currency string, amount float64, from, to Account) (tb TransferBalance, err error) {
+	return p.TransferBalanceContext(context.Background(), currency, amount, from, to)
+}
+
+// TransferBalanceContext is TransferBalance with a context for cancellation and deadlines
+func (p *Poloniex) TransferBalanceContext(ctx context.Context, currency string, amount float64, from, to Account) (tb TransferBalance, err error) {
+	if err = from.Validate(); err != nil {
+		return
+	}
+	if err = to.Validate(); err != nil {
+		return
+	}
+	a, err := amountFromFloat(amount)
+	if err != nil {
+		return
+	}
 	params := url.Values{}
 	params.Add("currency", currency)
-	params.Add("amount", toString(amount))
-	params.Add("fromAccount", from)
-	params.Add("toAccount", to)
-	fmt.Printf("%+v", params)
-	err = p.private("transferBalance", params, &tb)
+	params.Add("amount", a.String())
+	params.Add("fromAccount", string(from))
+	params.Add("toAccount", string(to))
+	err = p.private(ctx, "transferBalance", params, &tb)
 	return
 }
 
 func (p *Poloniex) MarginAccountSummary() (mas MarginAccountSummary, err error) {
-	err = p.private("returnMarginAccountSummary", nil, &mas)
+	return p.MarginAccountSummaryContext(context.Background())
+}
+
+// MarginAccountSummaryContext is MarginAccountSummary with a context for cancellation and deadlines
+func (p *Poloniex) MarginAccountSummaryContext(ctx context.Context) (mas MarginAccountSummary, err error) {
+	err = p.private(ctx, "returnMarginAccountSummary", nil, &mas)
 	return
 }
 
+// MarginBuy places a margin buy order, optionally with the maximum lendingRate to accept
+func (p *Poloniex) MarginBuy(pair string, rate, amount float64, lendingRate ...float64) (marginOrder MarginOrder, err error) {
+	return p.MarginBuyContext(context.Background(), pair, rate, amount, lendingRate...)
+}
+
+// MarginBuyContext is MarginBuy with a context for cancellation and deadlines
+func (p *Poloniex) MarginBuyContext(ctx context.Context, pair string, rate, amount float64, lendingRate ...float64) (marginOrder MarginOrder, err error) {
+	params, err := marginOrderParams(pair, rate, amount, lendingRate)
+	if err != nil {
+		return
+	}
+	err = p.private(ctx, "marginBuy", params, &marginOrder)
+	return
+}
+
+// MarginSell places a margin sell order, optionally with the maximum lendingRate to accept
+func (p *Poloniex) MarginSell(pair string, rate, amount float64, lendingRate ...float64) (marginOrder MarginOrder, err error) {
+	return p.MarginSellContext(context.Background(), pair, rate, amount, lendingRate...)
+}
+
+// MarginSellContext is MarginSell with a context for cancellation and deadlines
+func (p *Poloniex) MarginSellContext(ctx context.Context, pair string, rate, amount float64, lendingRate ...float64) (marginOrder MarginOrder, err error) {
+	params, err := marginOrderParams(pair, rate, amount, lendingRate)
+	if err != nil {
+		return
+	}
+	err = p.private(ctx, "marginSell", params, &marginOrder)
+	return
+}
+
+func marginOrderParams(pair string, rate, amount float64, lendingRate []float64) (url.Values, error) {
+	var f amountFields
+	params := url.Values{}
+	params.Add("currencyPair", pair)
+	params.Add("rate", f.parse(rate).String())
+	params.Add("amount", f.parse(amount).String())
+	if len(lendingRate) > 0 {
+		params.Add("lendingRate", f.parse(lendingRate[0]).String())
+	}
+	return params, f.err
+}
+
+// MarginPosition returns your margin position in pair
+func (p *Poloniex) MarginPosition(pair string) (marginPosition MarginPosition, err error) {
+	return p.MarginPositionContext(context.Background(), pair)
+}
+
+// MarginPositionContext is MarginPosition with a context for cancellation and deadlines
+func (p *Poloniex) MarginPositionContext(ctx context.Context, pair string) (marginPosition MarginPosition, err error) {
+	params := url.Values{}
+	params.Add("currencyPair", pair)
+	err = p.private(ctx, "getMarginPosition", params, &marginPosition)
+	return
+}
+
+// MarginPositionAll returns your margin positions in every pair
+func (p *Poloniex) MarginPositionAll() (marginPositions MarginPositions, err error) {
+	return p.MarginPositionAllContext(context.Background())
+}
+
+// MarginPositionAllContext is MarginPositionAll with a context for cancellation and deadlines
+func (p *Poloniex) MarginPositionAllContext(ctx context.Context) (marginPositions MarginPositions, err error) {
+	params := url.Values{}
+	params.Add("currencyPair", "all")
+	err = p.private(ctx, "getMarginPosition", params, &marginPositions)
+	return
+}
+
+// CloseMarginPosition closes your margin position in pair at market price
+func (p *Poloniex) CloseMarginPosition(pair string) (closeMarginPosition CloseMarginPosition, err error) {
+	return p.CloseMarginPositionContext(context.Background(), pair)
+}
+
+// CloseMarginPositionContext is CloseMarginPosition with a context for cancellation and deadlines
+func (p *Poloniex) CloseMarginPositionContext(ctx context.Context, pair string) (closeMarginPosition CloseMarginPosition, err error) {
+	params := url.Values{}
+	params.Add("currencyPair", pair)
+	err = p.private(ctx, "closeMarginPosition", params, &closeMarginPosition)
+	return
+}
+
+// IsOpen reports whether there is a position, Type is "none" otherwise
+func (mp MarginPosition) IsOpen() bool {
+	return mp.Type != "" && mp.Type != "none"
+}
+
+// HasLiquidationPrice reports whether the position can be liquidated, the API sends -1 when it can't
+func (mp MarginPosition) HasLiquidationPrice() bool {
+	return mp.LiquidationPrice.Sign() > 0
+}
+
 func (p *Poloniex) LoanOffer(currency string, amount float64, duration int, renew bool, lendingRate float64) (loanOffer LoanOffer, err error) {
+	return p.LoanOfferContext(context.Background(), currency, amount, duration, renew, lendingRate)
+}
+
+// LoanOfferContext is LoanOffer with a context for cancellation and deadlines
+func (p *Poloniex) LoanOfferContext(ctx context.Context, currency string, amount float64, duration int, renew bool, lendingRate float64) (loanOffer LoanOffer, err error) {
+	var f amountFields
 	params := url.Values{}
 	params.Add("currency", currency)
-	params.Add("amount", toString(amount))
-	params.Add("lendingRate", toString(lendingRate/100.0))
+	params.Add("amount", f.parse(amount).String())
+	params.Add("lendingRate", f.parse(lendingRate/100.0).String())
+	if err = f.err; err != nil {
+		return
+	}
 	params.Add("duration", fmt.Sprintf("%d", duration))
 	r := 0
 	if renew {
 		r = 1
 	}
 	params.Add("autoRenew", fmt.Sprintf("%d", r))
-	err = p.private("createLoanOffer", params, &loanOffer)
+	err = p.private(ctx, "createLoanOffer", params, &loanOffer)
 	return
 }
 
 func (p *Poloniex) CancelLoanOffer(orderNumber int64) (success bool, err error) {
+	return p.CancelLoanOfferContext(context.Background(), orderNumber)
+}
+
+// CancelLoanOfferContext is CancelLoanOffer with a context for cancellation and deadlines
+func (p *Poloniex) CancelLoanOfferContext(ctx context.Context, orderNumber int64) (success bool, err error) {
 	params := url.Values{}
 	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
 	b := Base{}
-	err = p.private("cancelLoanOffer", params, &b)
+	err = p.private(ctx, "cancelLoanOffer", params, &b)
 	success = b.Success == 1
 	return
 }
 
 func (p *Poloniex) OpenLoanOffers() (openLoanOffers OpenLoanOffers, err error) {
-	err = p.private("returnOpenLoanOffers", nil, &openLoanOffers)
+	return p.OpenLoanOffersContext(context.Background())
+}
+
+// OpenLoanOffersContext is OpenLoanOffers with a context for cancellation and deadlines
+func (p *Poloniex) OpenLoanOffersContext(ctx context.Context) (openLoanOffers OpenLoanOffers, err error) {
+	err = p.private(ctx, "returnOpenLoanOffers", nil, &openLoanOffers)
 	return
 }
 
 func (p *Poloniex) ActiveLoans() (activeLoans ActiveLoans, err error) {
-	err = p.private("returnActiveLoans", nil, &activeLoans)
-	provided := activeLoans.Provided
-	n := []ActiveLoan{}
-	for k := range provided {
-		v := provided[k]
-		v.Renewable = v.AutoRenew == 1
-		t, err := time.Parse("2006-01-02 15:04:05", v.Date)
-		if err == nil {
-			v.DateTaken = t
-		}
-		n = append(n, v)
+	return p.ActiveLoansContext(context.Background())
+}
+
+// ActiveLoansContext is ActiveLoans with a context for cancellation and deadlines
+func (p *Poloniex) ActiveLoansContext(ctx context.Context) (activeLoans ActiveLoans, err error) {
+	err = p.private(ctx, "returnActiveLoans", nil, &activeLoans)
+	return
+}
+
+//LendingHistory returns the lending history between start and end, limit caps the number of entries when above 0
+func (p *Poloniex) LendingHistory(start, end time.Time, limit int) (lendingHistory LendingHistory, err error) {
+	return p.LendingHistoryContext(context.Background(), start, end, limit)
+}
+
+// LendingHistoryContext is LendingHistory with a context for cancellation and deadlines
+func (p *Poloniex) LendingHistoryContext(ctx context.Context, start, end time.Time, limit int) (lendingHistory LendingHistory, err error) {
+	params := url.Values{}
+	params.Add("start", fmt.Sprintf("%d", start.Unix()))
+	params.Add("end", fmt.Sprintf("%d", end.Unix()))
+	if limit > 0 {
+		params.Add("limit", fmt.Sprintf("%d", limit))
 	}
-	activeLoans.Provided = n
+	err = p.private(ctx, "returnLendingHistory", params, &lendingHistory)
 	return
 }
 
 func (p *Poloniex) ToggleAutoRenew(orderNumber int64) (success bool, err error) {
+	return p.ToggleAutoRenewContext(context.Background(), orderNumber)
+}
+
+// ToggleAutoRenewContext is ToggleAutoRenew with a context for cancellation and deadlines
+func (p *Poloniex) ToggleAutoRenewContext(ctx context.Context, orderNumber int64) (success bool, err error) {
 	params := url.Values{}
 	params.Add("orderNumber", fmt.Sprintf("%d", orderNumber))
 	b := Base{}
-	err = p.private("toggleAutoRenew", params, &b)
+	err = p.private(ctx, "toggleAutoRenew", params, &b)
 	success = b.Success == 1
 	return
 }
 
 // make a call to the jsonrpc api, marshal into v
-func (p *Poloniex) private(method string, params url.Values, retval interface{}) error {
+func (p *Poloniex) private(ctx context.Context, method string, params url.Values, retval interface{}) error {
 	if p.debug {
-		defer un(trace("private: " + method))
+		defer p.un(trace("private: " + method))
 	}
-
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
 	if params == nil {
 		params = url.Values{}
 	}
-	params.Set("nonce", p.getNonce())
+	return p.retry(ctx, method, func() error {
+		return p.privateOnce(ctx, method, params, retval)
+	})
+}
+
+// privateOnce signs params with a fresh nonce and makes a single call.
+// The client lock is only held while the nonce is allocated and the request signed,
+// after that the request waits its turn to be sent, see dispatcher.
+func (p *Poloniex) privateOnce(ctx context.Context, method string, params url.Values, retval interface{}) error {
+	if err := p.waitRateLimit(ctx); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	nonce, err := p.getNonce()
+	if err != nil {
+		p.mutex.Unlock()
+		return err
+	}
+	params.Set("nonce", nonce)
 	params.Set("command", method)
 	postData := params.Encode()
+	sign := p.sign(postData)
+	ticket := p.dispatch.ticket()
+	p.mutex.Unlock()
 
-	req := goreq.Request{
-		Method:      "POST",
-		Uri:         PRIVATEURI,
-		Body:        postData,
-		ContentType: "application/x-www-form-urlencoded",
-		Accept:      "application/json",
-		Timeout:     130 * time.Second,
+	var once sync.Once
+	release := func() {
+		once.Do(func() { p.dispatch.release(ticket) })
 	}
+	if err := p.dispatch.wait(ctx, ticket); err != nil {
+		return err
+	}
+	defer release()
 
-	req.AddHeader("Sign", p.sign(postData))
-	req.AddHeader("Key", p.Key)
-	req.AddHeader("Content-Length", strconv.Itoa(len(postData)))
-
-	res, err := req.Do()
+	// let the next request go as soon as this one is on the wire, or when the call returns for transports without tracing
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) { release() },
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", p.privateURI, strings.NewReader(postData))
 	if err != nil {
 		return err
 	}
 
-	defer res.Body.Close()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Sign", sign)
+	req.Header.Set("Key", p.Key)
 
-	s, err := res.Body.ToString()
+	sByte, statusCode, err := p.do(req)
 	if err != nil {
 		return err
 	}
-	sByte := []byte(s)
 
 	if p.debug {
-		fmt.Println(s)
+		p.logger.Println(string(sByte))
 	}
 
-	jsonData, err := simplejson.NewJson(sByte)
-	if err != nil {
+	if err := checkResponse(method, statusCode, sByte); err != nil {
 		return err
 	}
 
-	// do we have an error message from the server?
-	jsonErr, ok := jsonData.CheckGet("error")
-	if ok {
-		// looks like we have an error from poloniex
-		return fmt.Errorf(jsonErr.MustString())
-	}
-
 	err = json.Unmarshal(sByte, retval)
 	if err != nil && retval == nil {
-		log.Println(err)
+		p.logger.Println(err)
 		return err
 	}
 	return err
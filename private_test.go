@@ -0,0 +1,212 @@
+package poloniex
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client talking to a stand-in server that answers each command with
+// the canned json in responses, and records the form sent with each call
+func newTestClient(t *testing.T, responses map[string]string, opts ...Option) (*Poloniex, *[]url.Values) {
+	var calls []url.Values
+	var mutex sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mutex.Lock()
+		calls = append(calls, r.Form)
+		mutex.Unlock()
+		response, ok := responses[r.Form.Get("command")]
+		if !ok {
+			t.Errorf("unexpected command %q", r.Form.Get("command"))
+			response = `{"error":"Invalid command."}`
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(ts.Close)
+
+	opts = append([]Option{WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(nil), WithRetryPolicy(RetryPolicy{})}, opts...)
+	p, err := NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, &calls
+}
+
+func TestMarginTrading(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{
+		"marginBuy": `{"success":1,"message":"Margin order placed.","orderNumber":"154407998",
+			"resultingTrades":{"BTC_DASH":[{"amount":"1.00000000","date":"2015-05-10 22:47:05","rate":"0.01383692","total":"0.01383692","tradeID":"1213556","type":"buy"}]}}`,
+		"getMarginPosition": `{"amount":"40.94717831","total":"-0.09671314","basePrice":"0.00236190","liquidationPrice":-1,"pl":"-0.00058655","lendingFees":"-0.00000038","type":"long"}`,
+		"closeMarginPosition": `{"success":1,"message":"Successfully closed margin position.",
+			"resultingTrades":{"BTC_XMR":[{"amount":"7.09215901","date":"2015-05-10 22:38:49","rate":"0.00235337","total":"0.01669047","tradeID":"1213346","type":"sell"}]}}`,
+	})
+
+	order, err := p.MarginBuy("BTC_DASH", 0.01383692, 1, 0.0002)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.OrderNumber != 154407998 || len(order.ResultingTrades["BTC_DASH"]) != 1 {
+		t.Fatalf("unexpected order %+v", order)
+	}
+	if order.ResultingTrades["BTC_DASH"][0].Total != MustParseAmount("0.01383692") {
+		t.Fatalf("unexpected trade %+v", order.ResultingTrades["BTC_DASH"][0])
+	}
+	if sent := (*calls)[0]; sent.Get("lendingRate") != "0.00020000" || sent.Get("rate") != "0.01383692" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	position, err := p.MarginPosition("BTC_DASH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !position.IsOpen() || position.HasLiquidationPrice() || position.ProfitLoss != MustParseAmount("-0.00058655") {
+		t.Fatalf("unexpected position %+v", position)
+	}
+
+	closed, err := p.CloseMarginPosition("BTC_XMR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Success != 1 || closed.ResultingTrades["BTC_XMR"][0].Amount != MustParseAmount("7.09215901") {
+		t.Fatalf("unexpected close %+v", closed)
+	}
+}
+
+func TestLending(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{
+		"returnLendingHistory": `[{"id":175589553,"currency":"BTC","rate":"0.00057400","amount":"0.04374404","duration":"0.47610000",
+			"interest":"0.00001196","fee":"-0.00000179","earned":"0.00001017","open":"2016-09-28 06:47:26","close":"2016-09-28 18:13:03"}]`,
+		"returnActiveLoans": `{"provided":[{"id":75073,"currency":"LTC","rate":"0.00020000","amount":"0.72234880","range":2,"autoRenew":1,"date":"2015-05-10 23:45:05","fees":"0.00006000"}],
+			"used":[{"id":75238,"currency":"BTC","rate":"0.00020000","amount":"0.04843834","range":2,"date":"2015-05-10 23:51:12","fees":"-0.00000001"}]}`,
+		"returnOpenLoanOffers": `{"BTC":[{"id":10595,"rate":"0.00020000","amount":"3.00000000","duration":2,"autoRenew":1,"date":"2015-05-10 23:33:50"}]}`,
+	})
+
+	start := time.Date(2016, 9, 1, 0, 0, 0, 0, time.UTC)
+	history, err := p.LendingHistory(start, start.AddDate(0, 1, 0), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Earned != MustParseAmount("0.00001017") || history[0].Duration != 0.4761 {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if !history[0].Close.Equal(time.Date(2016, 9, 28, 18, 13, 3, 0, time.UTC)) {
+		t.Fatalf("unexpected close time %s", history[0].Close)
+	}
+	if sent := (*calls)[0]; sent.Get("limit") != "10" || sent.Get("start") != "1472688000" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	loans, err := p.ActiveLoans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loans.Provided) != 1 || !loans.Provided[0].Renewable || len(loans.Used) != 1 || loans.Used[0].DateTaken.IsZero() {
+		t.Fatalf("unexpected loans %+v", loans)
+	}
+
+	offers, err := p.OpenLoanOffers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := offers["BTC"][0]; !o.Renewable || o.DateTaken.IsZero() {
+		t.Fatalf("unexpected offer %+v", o)
+	}
+}
+
+func TestBatchOrders(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{
+		"buy":             `{"orderNumber":"1001"}`,
+		"sell":            `{"error":"Not enough ETH."}`,
+		"cancelOrder":     `{"success":1}`,
+		"cancelAllOrders": `{"success":1,"message":"Orders canceled","orderNumbers":[503749,888321]}`,
+	})
+
+	results := p.PlaceOrders([]OrderRequest{
+		{Side: SideBuy, Pair: "BTC_ETH", Rate: MustParseAmount("0.031"), Amount: MustParseAmount("2")},
+		{Side: SideSell, Pair: "BTC_ETH", Rate: MustParseAmount("0.032"), Amount: MustParseAmount("2")},
+		{Side: "hold", Pair: "BTC_ETH"},
+	})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil || results[0].Order.OrderNumber != 1001 {
+		t.Errorf("unexpected buy result %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, InsufficientFunds) || results[1].Request.Side != SideSell {
+		t.Errorf("unexpected sell result %+v", results[1])
+	}
+	if results[2].Err == nil {
+		t.Errorf("expected an invalid side error")
+	}
+	if len(*calls) != 2 {
+		t.Errorf("expected the invalid order not to be sent, got %d calls", len(*calls))
+	}
+
+	cancels := p.CancelOrders([]int64{1, 2, 3})
+	for i, c := range cancels {
+		if c.Err != nil || !c.Success || c.OrderNumber != int64(i+1) {
+			t.Errorf("unexpected cancel result %+v", c)
+		}
+	}
+
+	cancelled, err := p.CancelAllOrdersAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled.OrderNumbers) != 2 || cancelled.OrderNumbers[1] != 888321 {
+		t.Fatalf("unexpected cancel all %+v", cancelled)
+	}
+	if last := (*calls)[len(*calls)-1]; last.Get("currencyPair") != "" {
+		t.Fatalf("expected no pair when cancelling all, got %v", last)
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	precision := map[string]int{"XMR": 4}
+	allowlist := map[string][]string{"XMR": {"4Jkuf"}}
+	p, calls := newTestClient(t, map[string]string{
+		"withdraw": `{"response":"Withdrew 2.00000000 XMR."}`,
+	},
+		WithWithdrawalPrecision(precision),
+		WithWithdrawalAllowlist(allowlist),
+	)
+	// the options keep their own copies
+	precision["XMR"] = 8
+	allowlist["XMR"][0] = "somewhere"
+	allowlist["BTC"] = []string{"1BoatSLRHtKNngkdXEeobR76b53LETtpyT"}
+
+	w, err := p.Withdraw(WithdrawRequest{Currency: "XMR", Amount: MustParseAmount("2"), Address: "4Jkuf", PaymentID: "ab12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Response != "Withdrew 2.00000000 XMR." {
+		t.Fatalf("unexpected response %+v", w)
+	}
+	if sent := (*calls)[0]; sent.Get("paymentId") != "ab12" || sent.Get("amount") != "2.00000000" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	_, err = p.Withdraw(WithdrawRequest{Currency: "XMR", Amount: MustParseAmount("2"), Address: "somewhere"})
+	if !errors.Is(err, ErrAddressNotAllowed) {
+		t.Fatalf("expected ErrAddressNotAllowed, got %v", err)
+	}
+	for _, req := range []WithdrawRequest{
+		{Currency: "XMR", Amount: MustParseAmount("0.00001"), Address: "4Jkuf"},
+		{Currency: "XMR", Amount: 0, Address: "4Jkuf"},
+		{Currency: "XMR", Amount: MustParseAmount("1")},
+		{Currency: "XMR", Amount: MustParseAmount("1"), Address: "somewhere"},
+		{Currency: "BTC", Amount: MustParseAmount("1"), Address: "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"},
+	} {
+		if _, err := p.Withdraw(req); err == nil {
+			t.Errorf("expected %+v to be rejected", req)
+		}
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("rejected withdrawals reached the server: %d calls", len(*calls))
+	}
+}
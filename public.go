@@ -1,31 +1,31 @@
 package poloniex
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"time"
 
-	simplejson "github.com/bitly/go-simplejson"
-	"github.com/franela/goreq"
 	"github.com/k0kubun/pp"
 )
 
 type (
 	Ticker      map[string]TickerEntry
 	TickerEntry struct {
-		Last        float64 `json:",string"`
-		Ask         float64 `json:"lowestAsk,string"`
-		Bid         float64 `json:"highestBid,string"`
+		Last        Amount
+		Ask         Amount  `json:"lowestAsk"`
+		Bid         Amount  `json:"highestBid"`
 		Change      float64 `json:"percentChange,string"`
-		BaseVolume  float64 `json:"baseVolume,string"`
-		QuoteVolume float64 `json:"quoteVolume,string"`
+		BaseVolume  Amount  `json:"baseVolume"`
+		QuoteVolume Amount  `json:"quoteVolume"`
 		IsFrozen    int64   `json:"isFrozen,string"`
 		ID          int64   `json:"id"`
 	}
 
 	DailyVolume          map[string]DailyVolumeEntry
-	DailyVolumeEntry     map[string]float64
+	DailyVolumeEntry     map[string]Amount
 	DailyVolumeTemp      map[string]interface{}
 	DailyVolumeEntryTemp map[string]interface{}
 
@@ -35,8 +35,8 @@ type (
 		IsFrozen bool
 	}
 	Order struct {
-		Rate   float64
-		Amount float64
+		Rate   Amount
+		Amount Amount
 	}
 
 	OrderBookTemp struct {
@@ -48,32 +48,35 @@ type (
 	OrderBookAll     map[string]OrderBook
 	OrderBookAllTemp map[string]OrderBookTemp
 
-	TradeHistory      []TradeHistoryEntry
+	TradeHistory []TradeHistoryEntry
+	//TradeHistoryEntry is a public trade, Date is in UTC
 	TradeHistoryEntry struct {
 		ID     int64 `json:"globalTradeID"`
-		Date   string
-		Type   string
-		Rate   float64 `json:",string"`
-		Amount float64 `json:",string"`
-		Total  float64 `json:",string"`
+		Date   time.Time
+		Type   Side
+		Rate   Amount
+		Amount Amount
+		Total  Amount
 	}
 
-	ChartData      []ChartDataEntry
+	ChartData []ChartDataEntry
+	//ChartDataEntry is a single candle, Date is the start of its period in UTC
 	ChartDataEntry struct {
-		Date            int64
-		High            float64
-		Low             float64
-		Open            float64
-		Close           float64
-		Volume          float64
-		QuoteVolume     float64
-		WeightedAverage float64
+		Date            time.Time
+		High            Amount
+		Low             Amount
+		Open            Amount
+		Close           Amount
+		Volume          Amount
+		QuoteVolume     Amount
+		WeightedAverage Amount
 	}
 
 	Currencies map[string]Currency
 	Currency   struct {
+		ID             int64 `json:"id"`
 		Name           string
-		TxFee          float64 `json:",string"`
+		TxFee          Amount
 		MinConf        float64
 		DepositAddress string
 		Disabled       int64
@@ -86,21 +89,31 @@ type (
 		Demands []LoanOrder
 	}
 	LoanOrder struct {
-		Rate     float64 `json:",string"`
-		Amount   float64 `json:",string"`
+		Rate     Amount
+		Amount   Amount
 		RangeMin float64
 		RangeMax float64
 	}
 )
 
 func (p *Poloniex) Ticker() (ticker Ticker, err error) {
-	err = p.public("returnTicker", nil, &ticker)
+	return p.TickerContext(context.Background())
+}
+
+// TickerContext is Ticker with a context for cancellation and deadlines
+func (p *Poloniex) TickerContext(ctx context.Context) (ticker Ticker, err error) {
+	err = p.public(ctx, "returnTicker", nil, &ticker)
 	return
 }
 
 func (p *Poloniex) DailyVolume() (dailyVolume DailyVolume, err error) {
+	return p.DailyVolumeContext(context.Background())
+}
+
+// DailyVolumeContext is DailyVolume with a context for cancellation and deadlines
+func (p *Poloniex) DailyVolumeContext(ctx context.Context) (dailyVolume DailyVolume, err error) {
 	dvt := DailyVolumeTemp{}
-	err = p.public("return24hVolume", nil, &dvt)
+	err = p.public(ctx, "return24hVolume", nil, &dvt)
 	if err != nil {
 		return
 	}
@@ -112,7 +125,9 @@ func (p *Poloniex) DailyVolume() (dailyVolume DailyVolume, err error) {
 		default:
 			v := i.(map[string]interface{})
 			for kk, vv := range v {
-				dve[kk] = toFloat(vv)
+				if dve[kk], err = toAmount(vv); err != nil {
+					return
+				}
 			}
 			dailyVolume[k] = dve
 		case string:
@@ -123,35 +138,52 @@ func (p *Poloniex) DailyVolume() (dailyVolume DailyVolume, err error) {
 }
 
 func (p *Poloniex) OrderBook(pair string) (orderBook OrderBook, err error) {
+	return p.OrderBookContext(context.Background(), pair)
+}
+
+// OrderBookContext is OrderBook with a context for cancellation and deadlines
+func (p *Poloniex) OrderBookContext(ctx context.Context, pair string) (orderBook OrderBook, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", pair)
 	params.Add("depth", "40")
 	obt := OrderBookTemp{}
-	err = p.public("returnOrderBook", params, &obt)
+	err = p.public(ctx, "returnOrderBook", params, &obt)
 	if err != nil {
 		return
 	}
-	orderBook = tempToOrderBook(obt)
+	orderBook, err = tempToOrderBook(obt)
 	return
 }
 
 func (p *Poloniex) OrderBookAll() (orderBook OrderBookAll, err error) {
+	return p.OrderBookAllContext(context.Background())
+}
+
+// OrderBookAllContext is OrderBookAll with a context for cancellation and deadlines
+func (p *Poloniex) OrderBookAllContext(ctx context.Context) (orderBook OrderBookAll, err error) {
 	params := url.Values{}
 	params.Add("depth", "5")
 	params.Add("currencyPair", "all")
 	obt := OrderBookAllTemp{}
-	err = p.public("returnOrderBook", params, &obt)
+	err = p.public(ctx, "returnOrderBook", params, &obt)
 	if err != nil {
 		return
 	}
 	orderBook = OrderBookAll{}
 	for k, v := range obt {
-		orderBook[k] = tempToOrderBook(v)
+		if orderBook[k], err = tempToOrderBook(v); err != nil {
+			return
+		}
 	}
 	return
 }
 
 func (p *Poloniex) TradeHistory(pair string, dates ...int64) (tradeHistory TradeHistory, err error) {
+	return p.TradeHistoryContext(context.Background(), pair, dates...)
+}
+
+// TradeHistoryContext is TradeHistory with a context for cancellation and deadlines
+func (p *Poloniex) TradeHistoryContext(ctx context.Context, pair string, dates ...int64) (tradeHistory TradeHistory, err error) {
 	params := url.Values{}
 	params.Add("currencyPair", pair)
 	if len(dates) > 0 {
@@ -162,122 +194,138 @@ func (p *Poloniex) TradeHistory(pair string, dates ...int64) (tradeHistory Trade
 		// we have an end date
 		params.Add("end", fmt.Sprintf("%d", dates[1]))
 	}
-	err = p.public("returnTradeHistory", params, &tradeHistory)
+	err = p.public(ctx, "returnTradeHistory", params, &tradeHistory)
 	return
 }
 
-func (p *Poloniex) ChartData(pair string) (chartData ChartData, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("start", fmt.Sprintf("%d", time.Now().Add(-24*time.Hour).Unix()))
-	params.Add("end", "9999999999")
-	params.Add("period", "300")
-	err = p.public("returnChartData", params, &chartData)
-	return
+//ChartData returns the candles for the last 24 hours, period defaults to Candle5m
+func (p *Poloniex) ChartData(pair string, period ...CandlePeriod) (chartData ChartData, err error) {
+	return p.ChartDataContext(context.Background(), pair, period...)
+}
+
+// ChartDataContext is ChartData with a context for cancellation and deadlines
+func (p *Poloniex) ChartDataContext(ctx context.Context, pair string, period ...CandlePeriod) (chartData ChartData, err error) {
+	return p.ChartDataPeriodContext(ctx, pair, time.Now().Add(-24*time.Hour), time.Unix(9999999999, 0), period...)
+}
+
+//ChartDataPeriod returns the candles between start and end, period defaults to Candle5m.
+//See ChartDataBackfill for ranges too long for a single call.
+func (p *Poloniex) ChartDataPeriod(pair string, start, end time.Time, period ...CandlePeriod) (chartData ChartData, err error) {
+	return p.ChartDataPeriodContext(context.Background(), pair, start, end, period...)
 }
 
-func (p *Poloniex) ChartDataPeriod(pair string, start, end time.Time, period ...int) (chartData ChartData, err error) {
+// ChartDataPeriodContext is ChartDataPeriod with a context for cancellation and deadlines
+func (p *Poloniex) ChartDataPeriodContext(ctx context.Context, pair string, start, end time.Time, period ...CandlePeriod) (chartData ChartData, err error) {
+	pi, err := candlePeriod(period)
+	if err != nil {
+		return
+	}
 	params := url.Values{}
 	params.Add("currencyPair", pair)
 	params.Add("start", fmt.Sprintf("%d", start.Unix()))
 	params.Add("end", fmt.Sprintf("%d", end.Unix()))
-	pi := 300
-	if len(period) > 0 {
-		pi = period[0]
-	}
-	ps := fmt.Sprintf("%d", pi)
-	params.Add("period", ps)
-	err = p.public("returnChartData", params, &chartData)
+	params.Add("period", fmt.Sprintf("%d", pi))
+	err = p.public(ctx, "returnChartData", params, &chartData)
 	return
 }
 
-func (p *Poloniex) ChartDataCurrent(pair string) (chartData ChartData, err error) {
-	params := url.Values{}
-	params.Add("currencyPair", pair)
-	params.Add("start", fmt.Sprintf("%d", time.Now().Add(-5*time.Minute).Unix()))
-	params.Add("end", "9999999999")
-	params.Add("period", "300")
-	err = p.public("returnChartData", params, &chartData)
-	return
+//ChartDataCurrent returns the latest candle, period defaults to Candle5m
+func (p *Poloniex) ChartDataCurrent(pair string, period ...CandlePeriod) (chartData ChartData, err error) {
+	return p.ChartDataCurrentContext(context.Background(), pair, period...)
+}
+
+// ChartDataCurrentContext is ChartDataCurrent with a context for cancellation and deadlines
+func (p *Poloniex) ChartDataCurrentContext(ctx context.Context, pair string, period ...CandlePeriod) (chartData ChartData, err error) {
+	pi, err := candlePeriod(period)
+	if err != nil {
+		return
+	}
+	return p.ChartDataPeriodContext(ctx, pair, time.Now().Add(-pi.Duration()), time.Unix(9999999999, 0), pi)
 }
 
 func (p *Poloniex) Currencies() (currencies Currencies, err error) {
-	err = p.public("returnCurrencies", nil, &currencies)
+	return p.CurrenciesContext(context.Background())
+}
+
+// CurrenciesContext is Currencies with a context for cancellation and deadlines
+func (p *Poloniex) CurrenciesContext(ctx context.Context) (currencies Currencies, err error) {
+	err = p.public(ctx, "returnCurrencies", nil, &currencies)
 	return
 }
 
 func (p *Poloniex) LoanOrders(currency string) (loanOrders LoanOrders, err error) {
+	return p.LoanOrdersContext(context.Background(), currency)
+}
+
+// LoanOrdersContext is LoanOrders with a context for cancellation and deadlines
+func (p *Poloniex) LoanOrdersContext(ctx context.Context, currency string) (loanOrders LoanOrders, err error) {
 	params := url.Values{}
 	params.Add("currency", currency)
-	err = p.public("returnLoanOrders", params, &loanOrders)
+	err = p.public(ctx, "returnLoanOrders", params, &loanOrders)
 	return
 }
 
-func tempToOrderBook(obt OrderBookTemp) (ob OrderBook) {
+func tempToOrderBook(obt OrderBookTemp) (ob OrderBook, err error) {
 	asks := obt.Asks
 	bids := obt.Bids
 	ob.IsFrozen = obt.IsFrozen.(string) != "0"
 	ob.Asks = []Order{}
 	ob.Bids = []Order{}
+	var f amountFields
 	for k := range asks {
 		v := asks[k]
-		price := toFloat(v[0])
-		amount := toFloat(v[1])
+		price := f.parse(v[0])
+		amount := f.parse(v[1])
 		o := Order{Rate: price, Amount: amount}
 		ob.Asks = append(ob.Asks, o)
 	}
 	for k := range bids {
 		v := bids[k]
-		price := toFloat(v[0])
-		amount := toFloat(v[1])
+		price := f.parse(v[0])
+		amount := f.parse(v[1])
 		o := Order{Rate: price, Amount: amount}
 		ob.Bids = append(ob.Bids, o)
 	}
+	err = f.err
 	return
 }
 
-func (p *Poloniex) public(command string, params url.Values, retval interface{}) (err error) {
+func (p *Poloniex) public(ctx context.Context, command string, params url.Values, retval interface{}) (err error) {
 	if p.debug {
-		defer un(trace("public: " + command))
+		defer p.un(trace("public: " + command))
 	}
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
 	if params == nil {
 		params = url.Values{}
 	}
-	params.Add("command", command)
-	req := goreq.Request{Uri: PUBLICURI, QueryString: params, Timeout: 130 * time.Second}
-	res, err := req.Do()
+	return p.retry(ctx, command, func() error {
+		return p.publicOnce(ctx, command, params, retval)
+	})
+}
+
+func (p *Poloniex) publicOnce(ctx context.Context, command string, params url.Values, retval interface{}) (err error) {
+	if err := p.waitRateLimit(ctx); err != nil {
+		return err
+	}
+	params.Set("command", command)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.publicURI+"?"+params.Encode(), nil)
 	if err != nil {
 		return
 	}
-	if p.debug {
-		pp.Println(res.Request.URL.String())
-	}
-
-	defer res.Body.Close()
-
-	s, err := res.Body.ToString()
+	sByte, statusCode, err := p.do(req)
 	if err != nil {
 		return
 	}
-	sByte := []byte(s)
-
 	if p.debug {
-		pp.Println(s)
+		pp.Println(req.URL.String())
 	}
 
-	jsonData, err := simplejson.NewJson(sByte)
-	if err != nil {
-		return err
+	if p.debug {
+		pp.Println(string(sByte))
 	}
 
-	// do we have an error message from the server?
-	jsonErr, ok := jsonData.CheckGet("error")
-	if ok {
-		// looks like we have an error from poloniex
-		return fmt.Errorf(jsonErr.MustString())
+	if err := checkResponse(command, statusCode, sByte); err != nil {
+		return err
 	}
 
-	return json.Unmarshal([]byte(s), retval)
+	return json.Unmarshal(sByte, retval)
 }
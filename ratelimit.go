@@ -0,0 +1,137 @@
+package poloniex
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// RateLimitMode decides what a RateLimiter does when it is out of tokens
+	RateLimitMode int
+
+	// RateLimiter is a token bucket applied to every REST call, a single limiter can be shared
+	// between several clients using the same key or IP address
+	RateLimiter struct {
+		mutex     sync.Mutex
+		rate      float64
+		burst     float64
+		tokens    float64
+		last      time.Time
+		mode      RateLimitMode
+		unlimited bool
+		stats     RateLimiterStats
+	}
+
+	// RateLimiterStats describes how much a RateLimiter has held calls back
+	RateLimiterStats struct {
+		Requests  int64
+		Waited    int64
+		Rejected  int64
+		TotalWait time.Duration
+		MaxWait   time.Duration
+	}
+)
+
+const (
+	// RateLimitBlock makes calls wait until the limiter allows them
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitFailFast makes calls fail with ErrRateLimitExceeded instead of waiting
+	RateLimitFailFast
+)
+
+// DefaultRequestsPerSecond is the rate used by the limiter every client gets unless WithRateLimiter is given
+const DefaultRequestsPerSecond = 6
+
+// ErrRateLimitExceeded is returned by calls made through a fail-fast limiter that has no tokens left
+var ErrRateLimitExceeded = errors.New("poloniex: client rate limit exceeded")
+
+// NewRateLimiter creates a limiter allowing perSecond calls on average with bursts of up to burst calls.
+// A perSecond of zero or less (or +Inf) gives a limiter that never holds calls back but still counts them in Stats.
+func NewRateLimiter(perSecond float64, burst int, mode RateLimitMode) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:      perSecond,
+		burst:     float64(burst),
+		tokens:    float64(burst),
+		last:      time.Now(),
+		mode:      mode,
+		unlimited: !(perSecond > 0) || math.IsInf(perSecond, 1),
+	}
+}
+
+// Wait takes a token, blocking until one is available or ctx is done.
+// A fail-fast limiter returns ErrRateLimitExceeded rather than blocking.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	r.mutex.Lock()
+	if r.unlimited {
+		r.stats.Requests++
+		r.mutex.Unlock()
+		return nil
+	}
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+	r.stats.Requests++
+
+	if r.tokens >= 1 {
+		r.tokens--
+		r.mutex.Unlock()
+		return nil
+	}
+	if r.mode == RateLimitFailFast {
+		r.stats.Rejected++
+		r.mutex.Unlock()
+		return ErrRateLimitExceeded
+	}
+
+	// reserve a token now so later callers queue up behind this one
+	wait := time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+	r.tokens--
+	r.stats.Waited++
+	r.stats.TotalWait += wait
+	if wait > r.stats.MaxWait {
+		r.stats.MaxWait = wait
+	}
+	r.mutex.Unlock()
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// hand the reservation back
+		r.mutex.Lock()
+		r.tokens++
+		r.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the limiter statistics
+func (r *RateLimiter) Stats() RateLimiterStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.stats
+}
+
+// RateLimiter returns the limiter in use by the client, or nil if calls are not limited
+func (p *Poloniex) RateLimiter() *RateLimiter {
+	return p.limiter
+}
+
+func (p *Poloniex) waitRateLimit(ctx context.Context) error {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Wait(ctx)
+}
@@ -0,0 +1,90 @@
+package poloniex
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterFailFast(t *testing.T) {
+	r := NewRateLimiter(1, 2, RateLimitFailFast)
+	for i := 0; i < 2; i++ {
+		if err := r.Wait(context.Background()); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if err := r.Wait(context.Background()); err != ErrRateLimitExceeded {
+		t.Fatalf("expected ErrRateLimitExceeded, got %v", err)
+	}
+	if s := r.Stats(); s.Requests != 3 || s.Rejected != 1 || s.Waited != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.Inf(1), math.NaN()} {
+		for _, mode := range []RateLimitMode{RateLimitBlock, RateLimitFailFast} {
+			r := NewRateLimiter(rate, 1, mode)
+			start := time.Now()
+			for i := 0; i < 5; i++ {
+				if err := r.Wait(context.Background()); err != nil {
+					t.Fatalf("rate %v: call %d: %v", rate, i, err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+				t.Fatalf("rate %v: unlimited limiter held calls back for %s", rate, elapsed)
+			}
+			if s := r.Stats(); s.Requests != 5 || s.Waited != 0 || s.Rejected != 0 {
+				t.Fatalf("rate %v: unexpected stats %+v", rate, s)
+			}
+		}
+	}
+}
+
+func TestRateLimiterBlock(t *testing.T) {
+	r := NewRateLimiter(20, 1, RateLimitBlock)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := r.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected calls to be spaced out, took %s", elapsed)
+	}
+	if s := r.Stats(); s.Waited != 2 || s.TotalWait <= 0 || s.MaxWait <= 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Wait(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRateLimiterShared(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(1, 1, RateLimitFailFast)
+	a, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(limiter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRateLimiter(limiter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Ticker(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Ticker(); err != ErrRateLimitExceeded {
+		t.Fatalf("expected the second client to share the limit, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package poloniex
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides how failed REST calls are retried.
+// Only idempotent reads (the return* commands) are retried after network errors, 5xx responses or rate limiting.
+// Nonce errors are retried for every command, since the exchange rejects those before doing anything.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts for a read, 1 or less disables retries
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubling for each one after
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration
+	// Jitter is the fraction (0-1) of each wait that is randomised
+	Jitter float64
+	// NonceRetries is how many times a call is re-signed after an invalid nonce error
+	NonceRetries int
+}
+
+// DefaultRetryPolicy is the policy every client gets unless WithRetryPolicy is given
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.2,
+	NonceRetries:   2,
+}
+
+var expectedNonceRe = regexp.MustCompile(`(?i)nonce must be greater than (\d+)`)
+
+// backoff returns the wait before retry number attempt (starting at 1)
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(rp.InitialBackoff) * math.Pow(2, float64(attempt-1))
+	if rp.MaxBackoff > 0 && d > float64(rp.MaxBackoff) {
+		d = float64(rp.MaxBackoff)
+	}
+	if rp.Jitter > 0 {
+		d -= d * rp.Jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// retry runs call until it succeeds or the client's RetryPolicy gives up
+func (p *Poloniex) retry(ctx context.Context, command string, call func() error) error {
+	attempt := 1
+	nonceRetries := 0
+	for {
+		err := call()
+		if err == nil {
+			return nil
+		}
+		if apiErr, ok := err.(*APIError); ok && apiErr.Kind == InvalidNonce && nonceRetries < p.retryPolicy.NonceRetries {
+			nonceRetries++
+			if n, ok := parseExpectedNonce(apiErr.Message); ok {
+				if err := p.nonceSource.Advance(n); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if attempt >= p.retryPolicy.MaxAttempts || !isIdempotent(command) || !isRetryable(ctx, err) {
+			return err
+		}
+
+		t := time.NewTimer(p.retryPolicy.backoff(attempt))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+		attempt++
+	}
+}
+
+func parseExpectedNonce(message string) (int64, bool) {
+	m := expectedNonceRe.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// isIdempotent reports whether command only reads state, and so is safe to send twice
+func isIdempotent(command string) bool {
+	return strings.HasPrefix(command, "return")
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch e := err.(type) {
+	case *APIError:
+		return e.StatusCode >= 500 || e.Kind == RateLimited
+	case *url.Error:
+		// transport failure from http.Client
+		return true
+	}
+	return false
+}
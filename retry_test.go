@@ -0,0 +1,85 @@
+package poloniex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, NonceRetries: 1}
+
+func TestRetryNonceRecovery(t *testing.T) {
+	var nonces []int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		n, _ := strconv.ParseInt(r.Form.Get("nonce"), 10, 64)
+		nonces = append(nonces, n)
+		if len(nonces) == 1 {
+			w.Write([]byte(`{"error":"Nonce must be greater than 9000000000000000000. You provided ` + r.Form.Get("nonce") + `."}`))
+			return
+		}
+		w.Write([]byte(`{"orderNumber":"31226040"}`))
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buy, err := p.Buy("BTC_ETH", 0.1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buy.OrderNumber != 31226040 {
+		t.Fatalf("unexpected order %+v", buy)
+	}
+	if len(nonces) != 2 || nonces[1] <= 9000000000000000000 {
+		t.Fatalf("expected a second call with a bumped nonce, got %v", nonces)
+	}
+}
+
+func TestRetryIdempotentOnly(t *testing.T) {
+	calls := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		command := r.Form.Get("command")
+		calls[command]++
+		if command == "returnCompleteBalances" && calls[command] == 3 {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	p, err := NewClient(WithBaseURL(ts.URL), WithLazyMarkets(), WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Balances(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Sell("BTC_ETH", 0.1, 1); err == nil {
+		t.Fatal("expected sell to fail")
+	}
+	if calls["returnCompleteBalances"] != 3 || calls["sell"] != 1 {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	rp := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, want := range []time.Duration{100, 200, 300, 300} {
+		if d := rp.backoff(attempt + 1); d != want*time.Millisecond {
+			t.Errorf("backoff(%d) = %s, want %s", attempt+1, d, want*time.Millisecond)
+		}
+	}
+	rp.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := rp.backoff(1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("jittered backoff %s out of range", d)
+		}
+	}
+}
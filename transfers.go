@@ -0,0 +1,209 @@
+package poloniex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// TransferPending is a deposit waiting for confirmations or a withdrawal not yet sent
+	TransferPending TransferStatus = "PENDING"
+	// TransferAwaitingApproval is a withdrawal waiting for email confirmation
+	TransferAwaitingApproval TransferStatus = "AWAITING APPROVAL"
+	// TransferComplete is a credited deposit or a sent withdrawal
+	TransferComplete TransferStatus = "COMPLETE"
+	// TransferCanceled is a withdrawal that was cancelled
+	TransferCanceled TransferStatus = "CANCELED"
+)
+
+// DefaultHistoryWindow is the span of each call made by the history iterators unless another is given
+const DefaultHistoryWindow = 30 * 24 * time.Hour
+
+type (
+	// TransferStatus is the state of a deposit or withdrawal
+	TransferStatus string
+
+	// Deposit is a single entry in DepositsWithdrawals
+	Deposit struct {
+		DepositNumber int64
+		Currency      string
+		Address       string
+		Amount        Amount
+		Confirmations int64
+		TXID          string `json:"txid"`
+		Timestamp     time.Time
+		Status        TransferStatus
+	}
+
+	// Withdrawal is a single entry in DepositsWithdrawals, TXID is split out of the status of completed withdrawals
+	Withdrawal struct {
+		WithdrawalNumber int64
+		Currency         string
+		Address          string
+		Amount           Amount
+		Fee              Amount
+		Timestamp        time.Time
+		Status           TransferStatus
+		TXID             string
+		IPAddress        string
+		PaymentID        string `json:"paymentID"`
+	}
+
+	// DepositsWithdrawalsIterator walks deposit and withdrawal history one window at a time, in the style of bufio.Scanner
+	DepositsWithdrawalsIterator struct {
+		p      *Poloniex
+		ctx    context.Context
+		from   time.Time
+		end    time.Time
+		window time.Duration
+		page   DepositsWithdrawals
+		err    error
+		// keys of the entries returned from the previous window, which overlaps this one by a second
+		seen map[string]bool
+	}
+)
+
+// Known reports whether s is one of the statuses listed above
+func (s TransferStatus) Known() bool {
+	switch s {
+	case TransferPending, TransferAwaitingApproval, TransferComplete, TransferCanceled:
+		return true
+	}
+	return false
+}
+
+func (d *Deposit) UnmarshalJSON(b []byte) error {
+	type deposit Deposit
+	raw := struct {
+		*deposit
+		Timestamp int64
+	}{deposit: (*deposit)(d)}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	d.Timestamp = time.Unix(raw.Timestamp, 0).UTC()
+	return nil
+}
+
+func (w *Withdrawal) UnmarshalJSON(b []byte) error {
+	type withdrawal Withdrawal
+	raw := struct {
+		*withdrawal
+		Timestamp int64
+		Status    string
+	}{withdrawal: (*withdrawal)(w)}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	w.Timestamp = time.Unix(raw.Timestamp, 0).UTC()
+	// completed withdrawals read "COMPLETE: <txid>"
+	status, txid := raw.Status, ""
+	if i := strings.Index(status, ":"); i >= 0 {
+		status, txid = status[:i], strings.TrimSpace(status[i+1:])
+	}
+	w.Status = TransferStatus(strings.TrimSpace(status))
+	w.TXID = txid
+	return nil
+}
+
+func (d Deposit) key() string {
+	if d.DepositNumber != 0 {
+		return fmt.Sprintf("d%d", d.DepositNumber)
+	}
+	return "d" + d.Currency + d.TXID + d.Address
+}
+
+func (w Withdrawal) key() string {
+	return fmt.Sprintf("w%d", w.WithdrawalNumber)
+}
+
+// DepositsWithdrawalsRange returns deposits and withdrawals made between start and end
+func (p *Poloniex) DepositsWithdrawalsRange(start, end time.Time) (depositsWithdrawals DepositsWithdrawals, err error) {
+	return p.DepositsWithdrawalsRangeContext(context.Background(), start, end)
+}
+
+// DepositsWithdrawalsRangeContext is DepositsWithdrawalsRange with a context for cancellation and deadlines
+func (p *Poloniex) DepositsWithdrawalsRangeContext(ctx context.Context, start, end time.Time) (depositsWithdrawals DepositsWithdrawals, err error) {
+	params := url.Values{}
+	params.Add("start", fmt.Sprintf("%d", start.Unix()))
+	params.Add("end", fmt.Sprintf("%d", end.Unix()))
+	err = p.private(ctx, "returnDepositsWithdrawals", params, &depositsWithdrawals)
+	return
+}
+
+// DepositsWithdrawalsIter returns an iterator over the history between start and end, fetched window at a time
+// from oldest to newest. A zero end means now and a window of zero or less means DefaultHistoryWindow.
+// A zero start is an error returned by Err.
+func (p *Poloniex) DepositsWithdrawalsIter(start, end time.Time, window time.Duration) *DepositsWithdrawalsIterator {
+	return p.DepositsWithdrawalsIterContext(context.Background(), start, end, window)
+}
+
+// DepositsWithdrawalsIterContext is DepositsWithdrawalsIter with a context for cancellation and deadlines
+func (p *Poloniex) DepositsWithdrawalsIterContext(ctx context.Context, start, end time.Time, window time.Duration) *DepositsWithdrawalsIterator {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if window <= 0 {
+		window = DefaultHistoryWindow
+	}
+	it := &DepositsWithdrawalsIterator{p: p, ctx: ctx, from: start, end: end, window: window}
+	if start.IsZero() {
+		it.err = errNoStart
+	}
+	return it
+}
+
+// Next fetches the next window holding any entries not seen before, it returns false at the end of the range or on error
+func (it *DepositsWithdrawalsIterator) Next() bool {
+	for it.err == nil && !it.from.After(it.end) {
+		to := it.from.Add(it.window)
+		if to.After(it.end) {
+			to = it.end
+		}
+		page, err := it.p.DepositsWithdrawalsRangeContext(it.ctx, it.from, to)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		// the next window starts on the second this one ended, entries from that second come back twice
+		if to.Equal(it.end) {
+			it.from = it.end.Add(time.Second)
+		} else {
+			it.from = to
+		}
+
+		seen := map[string]bool{}
+		it.page = DepositsWithdrawals{}
+		for _, d := range page.Deposits {
+			seen[d.key()] = true
+			if !it.seen[d.key()] {
+				it.page.Deposits = append(it.page.Deposits, d)
+			}
+		}
+		for _, w := range page.Withdrawals {
+			seen[w.key()] = true
+			if !it.seen[w.key()] {
+				it.page.Withdrawals = append(it.page.Withdrawals, w)
+			}
+		}
+		it.seen = seen
+		if len(it.page.Deposits)+len(it.page.Withdrawals) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Page returns the entries fetched by the last successful call to Next
+func (it *DepositsWithdrawalsIterator) Page() DepositsWithdrawals {
+	return it.page
+}
+
+// Err returns the error that stopped the iterator, if any
+func (it *DepositsWithdrawalsIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,53 @@
+package poloniex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDepositsWithdrawals(t *testing.T) {
+	p, calls := newTestClient(t, map[string]string{
+		"returnDepositsWithdrawals": `{
+			"deposits":[{"depositNumber":7397,"currency":"BTC","address":"1K6oYq","amount":"0.01006132","confirmations":10,
+				"txid":"17f819a9","timestamp":86400,"status":"COMPLETE"}],
+			"withdrawals":[{"withdrawalNumber":134933,"currency":"BTC","address":"1N2i5n","amount":"5.00010000","fee":"0.00010000",
+				"timestamp":86400,"status":"COMPLETE: 36e483ef","ipAddress":"127.0.0.1","paymentID":null}]}`,
+	})
+
+	dw, err := p.DepositsWithdrawalsRange(time.Unix(0, 0), time.Unix(2*86400, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, w := dw.Deposits[0], dw.Withdrawals[0]
+	if d.Status != TransferComplete || !d.Timestamp.Equal(time.Unix(86400, 0)) || d.Timestamp.Location() != time.UTC {
+		t.Fatalf("unexpected deposit %+v", d)
+	}
+	if w.Status != TransferComplete || w.TXID != "36e483ef" || w.Fee != MustParseAmount("0.0001") {
+		t.Fatalf("unexpected withdrawal %+v", w)
+	}
+	if sent := (*calls)[0]; sent.Get("start") != "0" || sent.Get("end") != "172800" {
+		t.Fatalf("unexpected params %v", sent)
+	}
+
+	// every window answers with the same entries, which sit on the boundary of the first two
+	*calls = nil
+	it := p.DepositsWithdrawalsIter(time.Unix(0, 0), time.Unix(3*86400, 0), 24*time.Hour)
+	pages := 0
+	for it.Next() {
+		pages++
+		if len(it.Page().Deposits) != 1 || len(it.Page().Withdrawals) != 1 {
+			t.Fatalf("unexpected page %+v", it.Page())
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 1 || len(*calls) != 3 {
+		t.Fatalf("expected 1 page from 3 calls, got %d from %d", pages, len(*calls))
+	}
+	for i, want := range []string{"0", "86400", "172800"} {
+		if got := (*calls)[i].Get("start"); got != want {
+			t.Errorf("window %d starts at %s, want %s", i, got, want)
+		}
+	}
+}
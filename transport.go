@@ -0,0 +1,32 @@
+package poloniex
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// RequestMiddleware is called with every REST request just before it is sent, e.g. to add headers or record calls.
+// Private requests are already signed at this point, so changing their body will invalidate the signature.
+type RequestMiddleware func(req *http.Request) error
+
+// do runs the middleware, sends req with the client's http.Client and reads the whole response
+func (p *Poloniex) do(req *http.Request) (body []byte, statusCode int, err error) {
+	for _, mw := range p.middleware {
+		if err = mw(req); err != nil {
+			return nil, 0, errors.Wrap(err, "request middleware failed")
+		}
+	}
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, res.StatusCode, nil
+}
@@ -0,0 +1,68 @@
+package poloniex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithTransportAndMiddleware(t *testing.T) {
+	var seen []string
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.Header.Get("X-Trace"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"BTC_ETH":{"last":"0.05","id":148}}`)),
+			Header:     http.Header{},
+		}, nil
+	})
+	trace := func(req *http.Request) error {
+		req.Header.Set("X-Trace", req.URL.Query().Get("command"))
+		return nil
+	}
+
+	original := &http.Client{}
+	p, err := NewClient(WithHTTPClient(original), WithTransport(transport), WithRequestMiddleware(trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original.Transport != nil {
+		t.Fatal("WithTransport changed the client passed to WithHTTPClient")
+	}
+	ticker, err := p.Ticker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticker["BTC_ETH"].Last != MustParseAmount("0.05") {
+		t.Fatalf("unexpected ticker %+v", ticker)
+	}
+	if len(seen) != 2 || seen[0] != "returnTicker" || seen[1] != "returnTicker" {
+		t.Fatalf("expected the markets load and the call to go through the middleware, got %v", seen)
+	}
+}
+
+func TestRequestMiddlewareError(t *testing.T) {
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("request sent despite middleware error")
+		return nil, nil
+	})
+	deny := func(req *http.Request) error {
+		return errors.New("denied")
+	}
+	p, err := NewClient(WithTransport(transport), WithRequestMiddleware(deny), WithLazyMarkets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Balances(); err == nil {
+		t.Fatal("expected the middleware error")
+	}
+}
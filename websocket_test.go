@@ -6,10 +6,11 @@ import (
 	"github.com/k0kubun/pp"
 )
 
-func ExampleWS() {
+func ExamplePoloniex_StartWS() {
 	p := NewWithCredentials("Key goes here", "secret goes here")
 	p.Subscribe("ticker")
 	p.Subscribe("USDT_BTC")
+	p.StartWS()
 
 	p.On("ticker", func(m WSTicker) {
 		pp.Println(m)
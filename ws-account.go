@@ -0,0 +1,308 @@
+package poloniex
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// accountChannel is the websocket channel for private account notifications
+const accountChannel = "1000"
+
+type (
+	// accountSubscription is a subscribe command signed like a private REST call
+	accountSubscription struct {
+		Command string `json:"command"`
+		Channel string `json:"channel"`
+		Key     string `json:"key"`
+		Payload string `json:"payload"`
+		Sign    string `json:"sign"`
+	}
+
+	// WSBalanceUpdate is emitted as "balance" when a wallet balance changes, Amount is the change
+	WSBalanceUpdate struct {
+		CurrencyID int64
+		Currency   string
+		Wallet     Account
+		Amount     Amount
+	}
+
+	// WSNewOrder is emitted as "order-new" when one of your limit orders is placed
+	WSNewOrder struct {
+		Pair           string
+		OrderNumber    int64
+		Side           Side
+		Rate           Amount
+		Amount         Amount
+		Date           time.Time
+		OriginalAmount Amount
+		ClientOrderID  int64
+	}
+
+	// WSOrderUpdate is emitted as "order-update" when one of your orders changes, Amount is what is left.
+	// Reason is "f" for a fill, "s" for a self trade or "c" for a cancel.
+	WSOrderUpdate struct {
+		OrderNumber   int64
+		Amount        Amount
+		Reason        string
+		ClientOrderID int64
+	}
+
+	// WSAccountTrade is emitted as "account-trade" when one of your orders trades.
+	// FundingType is 0 for exchange, 1 for borrowed, 2 for margin and 3 for lending funds.
+	WSAccountTrade struct {
+		TradeID       int64
+		Rate          Amount
+		Amount        Amount
+		FeeMultiplier Amount
+		FundingType   int64
+		OrderNumber   int64
+		Fee           Amount
+		Date          time.Time
+		ClientOrderID int64
+		Total         Amount
+	}
+
+	// WSError is emitted as "ws-error" when the exchange answers a command with an error. Channel is
+	// "1000" when the account subscription is refused, for instance over its nonce, and empty when
+	// the reply does not say which channel it is about.
+	WSError struct {
+		Channel string
+		Message string
+	}
+
+	// WSMarginUpdate is emitted as "margin-update" when a margin position changes
+	WSMarginUpdate struct {
+		OrderNumber   int64
+		Currency      string
+		Amount        Amount
+		ClientOrderID int64
+	}
+)
+
+// SubscribeAccount subscribes to the notifications for your account, which needs a key and secret.
+// The subscription is signed with a fresh nonce every time it is sent, including after a reconnect.
+// Subscribing to "account" by name or id does the same.
+func (p *Poloniex) SubscribeAccount() error {
+	return p.Subscribe("account")
+}
+
+// prepareAccount checks the account channel can be subscribed to and loads what its notifications need
+func (p *Poloniex) prepareAccount() error {
+	if p.Key == "" || p.Secret == "" {
+		return errors.New("account notifications need a key and secret")
+	}
+	return p.ensureCurrencies()
+}
+
+// sendSubscribe sends the command that subscribes to chid. The account subscription is signed like a private
+// REST call, so its nonce is allocated under the client lock and it waits its turn with them, see dispatcher.
+func (p *Poloniex) sendSubscribe(chid string) error {
+	if chid != accountChannel {
+		return p.sendWSMessage(subscription{Command: "subscribe", Channel: chid})
+	}
+	p.mutex.Lock()
+	nonce, err := p.getNonce()
+	if err != nil {
+		p.mutex.Unlock()
+		return err
+	}
+	payload := "nonce=" + nonce
+	message := accountSubscription{Command: "subscribe", Channel: chid, Key: p.Key, Payload: payload, Sign: p.sign(payload)}
+	ticket := p.dispatch.ticket()
+	p.mutex.Unlock()
+
+	if err := p.dispatch.wait(context.Background(), ticket); err != nil {
+		return err
+	}
+	defer p.dispatch.release(ticket)
+	return p.sendWSMessage(message)
+}
+
+// ensureCurrencies loads the currency id lookup used by balance updates
+func (p *Poloniex) ensureCurrencies() error {
+	p.currenciesMutex.Lock()
+	defer p.currenciesMutex.Unlock()
+	if p.currencyByID != nil {
+		return nil
+	}
+	currencies, err := p.Currencies()
+	if err != nil {
+		return errors.Wrap(err, "error getting currencies for lookups")
+	}
+	byID := map[int64]string{}
+	for name, c := range currencies {
+		byID[c.ID] = name
+	}
+	p.currencyByID = byID
+	return nil
+}
+
+func (p *Poloniex) currencyName(id int64) string {
+	p.currenciesMutex.Lock()
+	defer p.currenciesMutex.Unlock()
+	return p.currencyByID[id]
+}
+
+// handleAccount emits an event for each notification in an account channel message
+func (p *Poloniex) handleAccount(message []interface{}) {
+	if len(message) == 2 {
+		// [1000, 1] acknowledges the subscription and [1000, 0] refuses it
+		if ack, ok := message[1].(float64); ok && ack == 0 {
+			p.wsError(WSError{Channel: accountChannel, Message: "account subscription refused"})
+		}
+		return
+	}
+	if len(message) < 3 {
+		return
+	}
+	entries, ok := message[2].([]interface{})
+	if !ok {
+		return
+	}
+	for _, e := range entries {
+		v, ok := e.([]interface{})
+		if !ok || len(v) == 0 {
+			continue
+		}
+		kind, _ := v[0].(string)
+		switch kind {
+		case "b":
+			b, err := p.parseBalanceUpdate(v)
+			if err != nil {
+				p.logger.Println(err)
+				continue
+			}
+			p.Emit("balance", b)
+		case "n":
+			n, err := p.parseNewOrder(v)
+			if err != nil {
+				p.logger.Println(err)
+				continue
+			}
+			p.Emit("order-new", n)
+		case "o":
+			o, err := parseOrderUpdate(v)
+			if err != nil {
+				p.logger.Println(err)
+				continue
+			}
+			p.Emit("order-update", o)
+		case "t":
+			t, err := parseAccountTrade(v)
+			if err != nil {
+				p.logger.Println(err)
+				continue
+			}
+			p.Emit("account-trade", t)
+		case "m":
+			m, err := parseMarginUpdate(v)
+			if err != nil {
+				p.logger.Println(err)
+				continue
+			}
+			p.Emit("margin-update", m)
+		}
+	}
+}
+
+// ["b", currency id, wallet, amount]
+func (p *Poloniex) parseBalanceUpdate(v []interface{}) (WSBalanceUpdate, error) {
+	var f amountFields
+	b := WSBalanceUpdate{
+		CurrencyID: toInt64(field(v, 1)),
+		Amount:     f.parse(field(v, 3)),
+	}
+	if f.err != nil {
+		return b, errors.Wrap(f.err, "cannot parse balance update")
+	}
+	b.Currency = p.currencyName(b.CurrencyID)
+	switch toString(field(v, 2)) {
+	case "e":
+		b.Wallet = AccountExchange
+	case "m":
+		b.Wallet = AccountMargin
+	case "l":
+		b.Wallet = AccountLending
+	}
+	return b, nil
+}
+
+// ["o", order number, amount, reason, client order id]
+func parseOrderUpdate(v []interface{}) (WSOrderUpdate, error) {
+	var f amountFields
+	o := WSOrderUpdate{
+		OrderNumber:   toInt64(field(v, 1)),
+		Amount:        f.parse(field(v, 2)),
+		Reason:        toString(field(v, 3)),
+		ClientOrderID: toInt64(field(v, 4)),
+	}
+	return o, errors.Wrap(f.err, "cannot parse order update")
+}
+
+// ["m", order number, currency, amount, client order id]
+func parseMarginUpdate(v []interface{}) (WSMarginUpdate, error) {
+	var f amountFields
+	m := WSMarginUpdate{
+		OrderNumber:   toInt64(field(v, 1)),
+		Currency:      toString(field(v, 2)),
+		Amount:        f.parse(field(v, 3)),
+		ClientOrderID: toInt64(field(v, 4)),
+	}
+	return m, errors.Wrap(f.err, "cannot parse margin update")
+}
+
+// ["n", pair id, order number, type, rate, amount, date, original amount, client order id]
+func (p *Poloniex) parseNewOrder(v []interface{}) (WSNewOrder, error) {
+	var f amountFields
+	n := WSNewOrder{
+		Pair:           p.ByID[strconv.FormatInt(toInt64(field(v, 1)), 10)],
+		OrderNumber:    toInt64(field(v, 2)),
+		Side:           SideSell,
+		Rate:           f.parse(field(v, 4)),
+		Amount:         f.parse(field(v, 5)),
+		OriginalAmount: f.parse(field(v, 7)),
+		ClientOrderID:  toInt64(field(v, 8)),
+	}
+	if f.err != nil {
+		return n, errors.Wrap(f.err, "cannot parse new order")
+	}
+	if toInt64(field(v, 3)) == 1 {
+		n.Side = SideBuy
+	}
+	var err error
+	n.Date, err = parseDate(toString(field(v, 6)))
+	return n, err
+}
+
+// ["t", trade id, rate, amount, fee multiplier, funding type, order number, fee, date, client order id, total]
+func parseAccountTrade(v []interface{}) (WSAccountTrade, error) {
+	var f amountFields
+	t := WSAccountTrade{
+		TradeID:       toInt64(field(v, 1)),
+		Rate:          f.parse(field(v, 2)),
+		Amount:        f.parse(field(v, 3)),
+		FeeMultiplier: f.parse(field(v, 4)),
+		FundingType:   toInt64(field(v, 5)),
+		OrderNumber:   toInt64(field(v, 6)),
+		Fee:           f.parse(field(v, 7)),
+		ClientOrderID: toInt64(field(v, 9)),
+		Total:         f.parse(field(v, 10)),
+	}
+	if f.err != nil {
+		return t, errors.Wrap(f.err, "cannot parse account trade")
+	}
+	var err error
+	t.Date, err = parseDate(toString(field(v, 8)))
+	return t, err
+}
+
+// field returns v[i], or nil if v is too short, since older messages leave off the trailing fields
+func field(v []interface{}, i int) interface{} {
+	if i < len(v) {
+		return v[i]
+	}
+	return nil
+}
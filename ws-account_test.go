@@ -0,0 +1,142 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAccountNotifications(t *testing.T) {
+	p := newWSTestClient(t)
+	p.Key, p.Secret = "key", "secret"
+	p.currencyByID = map[int64]string{28: "BTC"}
+	ws := newFakeWS()
+	p.ws = ws
+	connected := make(chan string, 1)
+	p.On("connected", func(WSConnection) { connected <- "connected" })
+
+	if err := p.SubscribeAccount(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.StartWS(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, connected, "connected")
+	ws.mutex.Lock()
+	raw := ws.raw
+	ws.mutex.Unlock()
+	if len(raw) != 1 {
+		t.Fatalf("expected one signed subscription, got %v", raw)
+	}
+	var sent accountSubscription
+	if err := json.Unmarshal([]byte(raw[0]), &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Channel != "1000" || sent.Key != "key" || sent.Sign != p.sign(sent.Payload) || len(sent.Payload) < len("nonce=1") {
+		t.Fatalf("unexpected subscription %+v", sent)
+	}
+
+	var balance WSBalanceUpdate
+	var order WSNewOrder
+	var update WSOrderUpdate
+	var trade WSAccountTrade
+	var margin WSMarginUpdate
+	p.On("balance", func(b WSBalanceUpdate) { balance = b }).
+		On("order-new", func(n WSNewOrder) { order = n }).
+		On("order-update", func(o WSOrderUpdate) { update = o }).
+		On("account-trade", func(tr WSAccountTrade) { trade = tr }).
+		On("margin-update", func(m WSMarginUpdate) { margin = m })
+	feed(t, p, `[1000,"",[
+		["n",148,6083059,1,"0.03000000","2.00000000","2018-09-08 04:54:09","2.00000000",12345],
+		["b",28,"e","-0.06000000"],
+		["t",42,"0.03000000","0.50000000","0.00150000",0,6083059,"0.00002250","2018-09-08 05:54:09","12345","0.01500000"],
+		["o",6083059,"1.50000000","f"],
+		["m",6083059,"BTC","-0.50000000",null]]]`)
+
+	if balance.Currency != "BTC" || balance.Wallet != AccountExchange || balance.Amount != MustParseAmount("-0.06") {
+		t.Errorf("unexpected balance %+v", balance)
+	}
+	if order.Pair != "BTC_ETH" || order.Side != SideBuy || order.ClientOrderID != 12345 ||
+		!order.Date.Equal(time.Date(2018, 9, 8, 4, 54, 9, 0, time.UTC)) {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if trade.TradeID != 42 || trade.OrderNumber != 6083059 || trade.Total != MustParseAmount("0.015") || trade.ClientOrderID != 12345 {
+		t.Errorf("unexpected trade %+v", trade)
+	}
+	if update.Amount != MustParseAmount("1.5") || update.Reason != "f" {
+		t.Errorf("unexpected update %+v", update)
+	}
+	if margin.Currency != "BTC" || margin.Amount != MustParseAmount("-0.5") {
+		t.Errorf("unexpected margin update %+v", margin)
+	}
+
+	p.Key = ""
+	if err := p.SubscribeAccount(); err == nil {
+		t.Error("expected SubscribeAccount without credentials to fail")
+	}
+}
+
+func TestSubscribeAccountNeedsKey(t *testing.T) {
+	p := newWSTestClient(t)
+	for _, channel := range []string{"account", "1000"} {
+		if err := p.Subscribe(channel); err == nil {
+			t.Errorf("expected subscribing to %q without a key to fail", channel)
+		}
+	}
+	if p.isSubscribed(accountChannel) {
+		t.Fatal("account channel subscribed without a key")
+	}
+
+	p.Key, p.Secret = "key", "secret"
+	p.currencyByID = map[int64]string{28: "BTC"}
+	if err := p.Subscribe("1000"); err != nil || !p.isSubscribed(accountChannel) {
+		t.Fatalf("expected the account channel subscribed by id, err %v", err)
+	}
+}
+
+func TestAccountSubscriptionOrder(t *testing.T) {
+	p := newWSTestClient(t)
+	p.Key, p.Secret = "key", "secret"
+	p.currencyByID = map[int64]string{28: "BTC"}
+	ws := newFakeWS()
+	p.ws = ws
+	connected := make(chan string, 1)
+	p.On("connected", func(WSConnection) { connected <- "connected" })
+	if err := p.StartWS(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, connected, "connected")
+
+	// a private call holding an earlier nonce has not been sent yet
+	p.mutex.Lock()
+	earlier := p.dispatch.ticket()
+	p.mutex.Unlock()
+	done := make(chan error, 1)
+	go func() { done <- p.SubscribeAccount() }()
+	time.Sleep(30 * time.Millisecond)
+	if written := ws.takeWritten(); len(written) != 0 {
+		t.Fatalf("account subscription sent ahead of an earlier nonce %+v", written)
+	}
+	p.dispatch.release(earlier)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if written := ws.takeWritten(); len(written) != 1 || written[0].Channel != "1000" {
+		t.Fatalf("unexpected subscription %+v", written)
+	}
+
+	errs := make(chan WSError, 2)
+	p.On("ws-error", func(e WSError) { errs <- e })
+	ws.messages <- `[1000,0]`
+	ws.messages <- `{"error":"Permission denied."}`
+	for _, want := range []WSError{{Channel: "1000", Message: "account subscription refused"}, {Message: "Permission denied."}} {
+		select {
+		case e := <-errs:
+			if e != want {
+				t.Fatalf("unexpected error %+v, want %+v", e, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %+v", want)
+		}
+	}
+}
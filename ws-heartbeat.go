@@ -0,0 +1,83 @@
+package poloniex
+
+import (
+	"time"
+)
+
+// DefaultStaleTimeout is how long the websocket may go without any message, heartbeats included,
+// before it is taken to have stalled. Poloniex sends a heartbeat every second on a quiet connection.
+const DefaultStaleTimeout = 10 * time.Second
+
+// minStaleCheck is the shortest interval the watchdog checks the connection at, however short the stale timeout
+const minStaleCheck = 10 * time.Millisecond
+
+// heartbeatChannel is the channel Poloniex sends heartbeats on when nothing else is being sent
+const heartbeatChannel = "1010"
+
+// WSStale is emitted as "stale" when nothing has arrived for longer than the stale timeout,
+// just before the connection is dropped and remade
+type WSStale struct {
+	LastMessage time.Time
+	Silence     time.Duration
+}
+
+// setConnected records whether readWS has the connection up, the watchdog only times a live connection
+func (p *Poloniex) setConnected(connected bool) {
+	p.lastSeenMutex.Lock()
+	defer p.lastSeenMutex.Unlock()
+	p.wsConnected = connected
+	if connected {
+		p.lastMessage = time.Now()
+	}
+}
+
+// touch records that a message arrived on chid
+func (p *Poloniex) touch(chid string) {
+	now := time.Now()
+	p.lastSeenMutex.Lock()
+	defer p.lastSeenMutex.Unlock()
+	p.lastSeen[chid] = now
+	p.lastMessage = now
+}
+
+// LastMessage returns when a message, heartbeats included, last arrived on channel, given by name or id.
+// ok is false if nothing has arrived on it yet.
+func (p *Poloniex) LastMessage(channel string) (t time.Time, ok bool) {
+	if chid, found := p.ByName[channel]; found {
+		channel = chid
+	}
+	p.lastSeenMutex.Lock()
+	defer p.lastSeenMutex.Unlock()
+	t, ok = p.lastSeen[channel]
+	return
+}
+
+// watchStale drops and remakes the connection whenever it goes quiet for longer than the stale timeout
+func (p *Poloniex) watchStale() {
+	if p.staleTimeout <= 0 {
+		return
+	}
+	interval := p.staleTimeout / 4
+	if interval < minStaleCheck {
+		interval = minStaleCheck
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	// the last message of the silence already acted on, readWS marks the connection down and up again
+	// in its own time, and a new connection or message moves lastMessage on
+	var handled time.Time
+	for range t.C {
+		p.lastSeenMutex.Lock()
+		connected, last := p.wsConnected, p.lastMessage
+		p.lastSeenMutex.Unlock()
+		if !connected || last.Equal(handled) {
+			continue
+		}
+		if silence := time.Since(last); silence > p.staleTimeout {
+			p.logger.Printf("websocket silent for %s, reconnecting\n", silence)
+			p.Emit("stale", WSStale{LastMessage: last, Silence: silence})
+			handled = last
+			p.ws.CloseAndReconnect()
+		}
+	}
+}
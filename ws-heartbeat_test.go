@@ -0,0 +1,69 @@
+package poloniex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleConnection(t *testing.T) {
+	p := newWSTestClient(t)
+	p.staleTimeout = 100 * time.Millisecond
+	ws := newFakeWS()
+	p.ws = ws
+	events := make(chan string, 10)
+	for _, e := range []string{"connected", "stale", "disconnected", "reconnected"} {
+		e := e
+		p.On(e, func(interface{}) { events <- e })
+	}
+	if err := p.StartWS(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, events, "connected")
+
+	// heartbeats keep a quiet connection alive
+	for i := 0; i < 6; i++ {
+		ws.messages <- `[1010]`
+		time.Sleep(40 * time.Millisecond)
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected %q while heartbeats arrive", e)
+	default:
+	}
+	if last, ok := p.LastMessage("heartbeat"); !ok || time.Since(last) > time.Second {
+		t.Fatalf("heartbeat not recorded, last %s", last)
+	}
+	if _, ok := p.LastMessage("BTC_ETH"); ok {
+		t.Fatal("no message has arrived on BTC_ETH")
+	}
+
+	// then silence
+	waitFor(t, events, "stale")
+	waitFor(t, events, "disconnected")
+	waitFor(t, events, "connected")
+	waitFor(t, events, "reconnected")
+}
+
+func TestStaleTimeoutShorterThanCheck(t *testing.T) {
+	// a timeout under 4ns used to give the watchdog's ticker a zero interval
+	p := newWSTestClient(t)
+	if err := WithStaleTimeout(3 * time.Nanosecond)(p); err != nil {
+		t.Fatal(err)
+	}
+	p.ws = newFakeWS()
+	stale := make(chan WSStale, 1)
+	p.On("stale", func(s WSStale) {
+		select {
+		case stale <- s:
+		default:
+		}
+	})
+	if err := p.StartWS(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stale:
+	case <-time.After(time.Second):
+		t.Fatal("expected the watchdog to run")
+	}
+}
@@ -0,0 +1,226 @@
+package poloniex
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// LiveOrderBook is an order book for one pair kept up to date from the websocket stream.
+// It is filled by the snapshot sent when the pair is subscribed and then by every update after it,
+// and is safe to read from many goroutines while the stream writes to it.
+type LiveOrderBook struct {
+	mutex sync.RWMutex
+	pair  string
+	ready bool
+	// asks lowest rate first, bids highest rate first
+	asks []Order
+	bids []Order
+}
+
+// LiveOrderBook returns the live book for pair, subscribing to the pair if needed.
+// The book is empty until the snapshot arrives, see Ready. Each change to it emits
+// "book-updated" and "<pair>-book-updated" with the *LiveOrderBook.
+func (p *Poloniex) LiveOrderBook(pair string) (*LiveOrderBook, error) {
+	if err := p.ensureMarkets(); err != nil {
+		return nil, err
+	}
+	chid, ok := p.ByName[pair]
+	if !ok {
+		return nil, errors.Errorf("unrecognised pair %s for live order book", pair)
+	}
+
+	p.booksMutex.Lock()
+	book, ok := p.books[pair]
+	if !ok {
+		book = &LiveOrderBook{pair: pair}
+		p.books[pair] = book
+	}
+	p.booksMutex.Unlock()
+	if ok {
+		return book, nil
+	}
+
+	if p.isSubscribed(chid) && p.started() {
+		// the snapshot for this subscription has been and gone, subscribe again to get another
+		if err := p.sendWhileConnected(p.sendWSMessage(subscription{Command: "unsubscribe", Channel: chid})); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Subscribe(pair); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// liveOrderBook returns the live book kept for pair, or nil if nobody asked for one
+func (p *Poloniex) liveOrderBook(pair string) *LiveOrderBook {
+	p.booksMutex.Lock()
+	defer p.booksMutex.Unlock()
+	return p.books[pair]
+}
+
+// updateLiveOrderBook applies a websocket orderbook message to the live book for its pair, if there is one
+func (p *Poloniex) updateLiveOrderBook(updates []WSOrderbook) {
+	if len(updates) == 0 {
+		return
+	}
+	book := p.liveOrderBook(updates[0].Pair)
+	if book != nil && book.apply(updates) {
+		p.Emit("book-updated", book).Emit(book.pair+"-book-updated", book)
+	}
+}
+
+// apply updates the book, reporting whether anything changed. Updates before the first snapshot are dropped.
+func (b *LiveOrderBook) apply(updates []WSOrderbook) (changed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, u := range updates {
+		switch u.Event {
+		case "snapshot":
+			b.asks = append([]Order(nil), u.Book.Asks...)
+			b.bids = append([]Order(nil), u.Book.Bids...)
+			b.ready = true
+		case "modify", "remove":
+			if !b.ready {
+				continue
+			}
+			if u.Type == "ask" {
+				b.asks = setLevel(b.asks, u.Rate, u.Amount, func(a, b Amount) bool { return a < b })
+			} else {
+				b.bids = setLevel(b.bids, u.Rate, u.Amount, func(a, b Amount) bool { return a > b })
+			}
+		default:
+			continue
+		}
+		changed = true
+	}
+	return
+}
+
+// reset empties the book until the next snapshot
+func (b *LiveOrderBook) reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.asks, b.bids, b.ready = nil, nil, false
+}
+
+// setLevel sets the amount at rate in levels ordered by before, a zero amount removes the level
+func setLevel(levels []Order, rate, amount Amount, before func(a, b Amount) bool) []Order {
+	i := sort.Search(len(levels), func(i int) bool { return !before(levels[i].Rate, rate) })
+	if i < len(levels) && levels[i].Rate == rate {
+		if amount.IsZero() {
+			return append(levels[:i], levels[i+1:]...)
+		}
+		levels[i].Amount = amount
+		return levels
+	}
+	if amount.IsZero() {
+		return levels
+	}
+	levels = append(levels, Order{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = Order{Rate: rate, Amount: amount}
+	return levels
+}
+
+// Pair returns the pair the book is for
+func (b *LiveOrderBook) Pair() string {
+	return b.pair
+}
+
+// Ready reports whether the snapshot has arrived, before then the book is empty
+func (b *LiveOrderBook) Ready() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.ready
+}
+
+// BestBid returns the highest bid, ok is false if there are no bids
+func (b *LiveOrderBook) BestBid() (order Order, ok bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if len(b.bids) == 0 {
+		return
+	}
+	return b.bids[0], true
+}
+
+// BestAsk returns the lowest ask, ok is false if there are no asks
+func (b *LiveOrderBook) BestAsk() (order Order, ok bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if len(b.asks) == 0 {
+		return
+	}
+	return b.asks[0], true
+}
+
+// Depth returns a copy of the best n levels on each side, best first like the REST OrderBook
+func (b *LiveOrderBook) Depth(n int) OrderBook {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return OrderBook{Asks: topLevels(b.asks, n), Bids: topLevels(b.bids, n)}
+}
+
+// Snapshot returns a copy of the whole book, best first like the REST OrderBook
+func (b *LiveOrderBook) Snapshot() OrderBook {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return OrderBook{Asks: topLevels(b.asks, len(b.asks)), Bids: topLevels(b.bids, len(b.bids))}
+}
+
+func topLevels(levels []Order, n int) []Order {
+	if n > len(levels) {
+		n = len(levels)
+	}
+	if n < 0 {
+		n = 0
+	}
+	return append([]Order{}, levels[:n]...)
+}
+
+// parseBookSnapshot reads the body of an "i" message, {"currencyPair":..., "orderBook":[asks, bids]}
+// with each side a map of rate to amount
+func parseBookSnapshot(raw interface{}) (OrderBook, error) {
+	book := OrderBook{}
+	body, ok := raw.(map[string]interface{})
+	if !ok {
+		return book, errors.New("cannot parse orderbook snapshot")
+	}
+	sides, ok := body["orderBook"].([]interface{})
+	if !ok || len(sides) != 2 {
+		return book, errors.New("cannot parse orderbook snapshot - missing orderBook")
+	}
+	var err error
+	if book.Asks, err = parseBookSide(sides[0]); err != nil {
+		return book, err
+	}
+	if book.Bids, err = parseBookSide(sides[1]); err != nil {
+		return book, err
+	}
+	sort.Slice(book.Asks, func(i, j int) bool { return book.Asks[i].Rate < book.Asks[j].Rate })
+	sort.Slice(book.Bids, func(i, j int) bool { return book.Bids[i].Rate > book.Bids[j].Rate })
+	return book, nil
+}
+
+func parseBookSide(raw interface{}) ([]Order, error) {
+	levels, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("cannot parse orderbook snapshot - invalid side")
+	}
+	orders := make([]Order, 0, len(levels))
+	for rate, amount := range levels {
+		r, err := ParseAmount(rate)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse orderbook snapshot")
+		}
+		a, err := toAmount(amount)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse orderbook snapshot")
+		}
+		orders = append(orders, Order{Rate: r, Amount: a})
+	}
+	return orders, nil
+}
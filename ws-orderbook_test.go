@@ -0,0 +1,82 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+// newWSTestClient returns a client that knows a single market, BTC_ETH on channel 148, and never dials
+func newWSTestClient(t *testing.T) *Poloniex {
+	p, err := NewClient(WithLazyMarkets(), WithLogger(log.New(ioutil.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ByID = map[string]string{"148": "BTC_ETH", "1000": "account", "1002": "ticker", "1010": "heartbeat"}
+	p.ByName = map[string]string{"BTC_ETH": "148", "account": "1000", "ticker": "1002", "heartbeat": "1010"}
+	return p
+}
+
+// feed passes a raw websocket message to the client as if it had been read from the connection
+func feed(t *testing.T, p *Poloniex, raw string) {
+	message := []interface{}{}
+	if err := json.Unmarshal([]byte(raw), &message); err != nil {
+		t.Fatal(err)
+	}
+	p.handleWSMessage(message)
+}
+
+func TestLiveOrderBook(t *testing.T) {
+	p := newWSTestClient(t)
+	book, err := p.LiveOrderBook("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.subscriptions["148"] {
+		t.Fatal("expected the pair to be subscribed")
+	}
+	updates := 0
+	p.On("BTC_ETH-book-updated", func(b *LiveOrderBook) {
+		updates++
+	})
+	var trade WSOrderbook
+	p.On("BTC_ETH-trade", func(o WSOrderbook) {
+		trade = o
+	})
+
+	feed(t, p, `[148,1,[["o",1,"0.0300","1.0"]]]`)
+	if book.Ready() {
+		t.Fatal("book should not be ready before the snapshot")
+	}
+
+	feed(t, p, `[148,2,[["i",{"currencyPair":"BTC_ETH","orderBook":[
+		{"0.0310":"2.0","0.0320":"3.0","0.0315":"1.5"},
+		{"0.0300":"1.0","0.0290":"4.0","0.0305":"0.5"}]}]]]`)
+	feed(t, p, `[148,3,[["o",0,"0.0310","0.00000000"],["o",1,"0.0307","2.5"],["t","126",0,"0.0310","2.0",1496860438]]]`)
+
+	if !book.Ready() || updates != 2 {
+		t.Fatalf("expected a ready book after 2 updates, got %v after %d", book.Ready(), updates)
+	}
+	if trade.TradeID != 126 || trade.Type != "sell" || trade.Amount != MustParseAmount("2") {
+		t.Fatalf("unexpected trade %+v", trade)
+	}
+	if ask, ok := book.BestAsk(); !ok || ask.Rate != MustParseAmount("0.0315") {
+		t.Fatalf("unexpected best ask %+v", ask)
+	}
+	if bid, ok := book.BestBid(); !ok || bid.Rate != MustParseAmount("0.0307") || bid.Amount != MustParseAmount("2.5") {
+		t.Fatalf("unexpected best bid %+v", bid)
+	}
+	depth := book.Depth(2)
+	if len(depth.Asks) != 2 || len(depth.Bids) != 2 || depth.Bids[1].Rate != MustParseAmount("0.0305") {
+		t.Fatalf("unexpected depth %+v", depth)
+	}
+	snapshot := book.Snapshot()
+	if len(snapshot.Asks) != 2 || len(snapshot.Bids) != 4 || snapshot.Bids[3].Rate != MustParseAmount("0.0290") {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	snapshot.Bids[0].Amount = 0
+	if bid, _ := book.BestBid(); bid.Amount.IsZero() {
+		t.Fatal("snapshot shares memory with the book")
+	}
+}
@@ -2,7 +2,6 @@ package poloniex
 
 import (
 	"encoding/json"
-	"log"
 
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
@@ -22,7 +21,9 @@ func (p *Poloniex) sendWSMessage(msg interface{}) error {
 	if err != nil {
 		return errors.Wrap(err, "marshalling WSmessage failed")
 	}
-	log.Println(string(msgs))
+	if p.debug {
+		p.logger.Println(string(msgs))
+	}
 
 	err = p.ws.WriteMessage(websocket.TextMessage, msgs)
 	if err != nil {
@@ -34,17 +35,22 @@ func (p *Poloniex) sendWSMessage(msg interface{}) error {
 //makeTickerHandler takes a WS Order or Trade and send it over the channel sepcified by the user
 func (p *Poloniex) messageHandler(ch chan WSTicker) turnpike.EventHandler {
 	return func(p []interface{}, n map[string]interface{}) {
+		var f amountFields
 		t := WSTicker{
 			Pair:          p[0].(string),
-			Last:          toFloat(p[1]),
-			Ask:           toFloat(p[2]),
-			Bid:           toFloat(p[3]),
+			Last:          f.parse(p[1]),
+			Ask:           f.parse(p[2]),
+			Bid:           f.parse(p[3]),
 			PercentChange: toFloat(p[4]) * 100.0,
-			BaseVolume:    toFloat(p[5]),
-			QuoteVolume:   toFloat(p[6]),
+			BaseVolume:    f.parse(p[5]),
+			QuoteVolume:   f.parse(p[6]),
 			IsFrozen:      toFloat(p[7]) != 0.0,
-			DailyHigh:     toFloat(p[8]),
-			DailyLow:      toFloat(p[9]),
+			DailyHigh:     f.parse(p[8]),
+			DailyLow:      f.parse(p[9]),
+		}
+		if f.err != nil {
+			// no way to report it from here, drop the ticker rather than send zeros
+			return
 		}
 		ch <- t
 	}
@@ -0,0 +1,165 @@
+package poloniex
+
+import (
+	"time"
+)
+
+// DefaultReorderWindow is how long an orderbook message that arrives early is held for the ones before it
+const DefaultReorderWindow = time.Second
+
+// maxReorderBuffer is the most early messages held per channel before a gap is declared anyway
+const maxReorderBuffer = 100
+
+type (
+	// WSResync is emitted as "resync" and "<pair>-resync" when messages on an orderbook channel went missing.
+	// The live book for the pair is cleared and the channel subscribed again to get a fresh snapshot.
+	WSResync struct {
+		Pair     string
+		Expected int64
+		Received int64
+	}
+
+	// channelSequence tracks the sequence numbers seen on one orderbook channel
+	channelSequence struct {
+		last    int64
+		pending map[int64]pendingMessage
+		// expiry declares the gap once the oldest pending message has waited out the reorder window,
+		// so a channel that goes quiet after losing a message is still resynced
+		expiry *time.Timer
+	}
+
+	pendingMessage struct {
+		updates []WSOrderbook
+		at      time.Time
+	}
+)
+
+// sequence puts the message numbered seq on channel chid in order. It returns the messages that can be
+// handled now, oldest first, or a WSResync if the messages in between are taken to be lost.
+func (p *Poloniex) sequence(chid string, seq int64, updates []WSOrderbook) ([][]WSOrderbook, *WSResync) {
+	p.sequencesMutex.Lock()
+	defer p.sequencesMutex.Unlock()
+	cs, ok := p.sequences[chid]
+	if !ok {
+		cs = &channelSequence{pending: map[int64]pendingMessage{}}
+		p.sequences[chid] = cs
+	}
+
+	if cs.last == 0 || isSnapshot(updates) {
+		// a snapshot starts the channel over, anything held back is older than it
+		cs.last = seq
+		cs.pending = map[int64]pendingMessage{}
+		cs.stopExpiry()
+		return [][]WSOrderbook{updates}, nil
+	}
+	if seq <= cs.last {
+		// seen already
+		return nil, nil
+	}
+	if seq == cs.last+1 {
+		ready := [][]WSOrderbook{updates}
+		cs.last = seq
+		for {
+			next, ok := cs.pending[cs.last+1]
+			if !ok {
+				break
+			}
+			ready = append(ready, next.updates)
+			delete(cs.pending, cs.last+1)
+			cs.last++
+		}
+		if len(cs.pending) == 0 {
+			cs.stopExpiry()
+		}
+		return ready, nil
+	}
+
+	now := time.Now()
+	cs.pending[seq] = pendingMessage{updates: updates, at: now}
+	if len(cs.pending) <= maxReorderBuffer && now.Sub(cs.oldestPending()) <= p.reorderWindow {
+		if cs.expiry == nil {
+			cs.expiry = time.AfterFunc(p.reorderWindow, func() { p.expireSequence(chid, cs) })
+		}
+		return nil, nil
+	}
+	resync := &WSResync{Expected: cs.last + 1, Received: seq}
+	if len(updates) > 0 {
+		resync.Pair = updates[0].Pair
+	}
+	cs.stopExpiry()
+	delete(p.sequences, chid)
+	return nil, resync
+}
+
+// expireSequence runs when the reorder window of channel chid may have passed without the missing
+// messages arriving. It resyncs the channel if so, or waits again for the oldest message still held.
+func (p *Poloniex) expireSequence(chid string, cs *channelSequence) {
+	p.sequencesMutex.Lock()
+	if p.sequences[chid] != cs || len(cs.pending) == 0 {
+		// resynced, reset or caught up since the timer was set
+		p.sequencesMutex.Unlock()
+		return
+	}
+	if wait := p.reorderWindow - time.Since(cs.oldestPending()); wait > 0 {
+		cs.expiry.Reset(wait)
+		p.sequencesMutex.Unlock()
+		return
+	}
+	resync := WSResync{Expected: cs.last + 1}
+	for seq, m := range cs.pending {
+		if resync.Received == 0 || seq < resync.Received {
+			resync.Received = seq
+			if len(m.updates) > 0 {
+				resync.Pair = m.updates[0].Pair
+			}
+		}
+	}
+	cs.expiry = nil
+	delete(p.sequences, chid)
+	p.sequencesMutex.Unlock()
+	p.resync(chid, resync)
+}
+
+// oldestPending returns when the longest held message arrived
+func (cs *channelSequence) oldestPending() time.Time {
+	oldest := time.Now()
+	for _, m := range cs.pending {
+		if m.at.Before(oldest) {
+			oldest = m.at
+		}
+	}
+	return oldest
+}
+
+func (cs *channelSequence) stopExpiry() {
+	if cs.expiry != nil {
+		cs.expiry.Stop()
+		cs.expiry = nil
+	}
+}
+
+func isSnapshot(updates []WSOrderbook) bool {
+	for _, u := range updates {
+		if u.Event == "snapshot" {
+			return true
+		}
+	}
+	return false
+}
+
+// resync clears the state kept for an orderbook channel that lost messages and subscribes to it again
+func (p *Poloniex) resync(chid string, r WSResync) {
+	if book := p.liveOrderBook(r.Pair); book != nil {
+		book.reset()
+	}
+	p.Emit("resync", r).Emit(r.Pair+"-resync", r)
+	if !p.started() || !p.isSubscribed(chid) {
+		return
+	}
+	for _, command := range []string{"unsubscribe", "subscribe"} {
+		if err := p.sendWSMessage(subscription{Command: command, Channel: chid}); err != nil {
+			p.logger.Println("resync:", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package poloniex
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequenceGaps(t *testing.T) {
+	p := newWSTestClient(t)
+	// long enough that only messages arriving decide when the window has passed
+	p.reorderWindow = time.Minute
+	book, err := p.LiveOrderBook("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	var resyncs []WSResync
+	p.On("BTC_ETH-resync", func(r WSResync) {
+		mutex.Lock()
+		resyncs = append(resyncs, r)
+		mutex.Unlock()
+	})
+
+	feed(t, p, `[148,10,[["i",{"currencyPair":"BTC_ETH","orderBook":[{"0.0310":"2.0"},{"0.0300":"1.0"}]}]]]`)
+	// 12 arrives before 11, so it waits for it, and 11 arriving twice is ignored
+	feed(t, p, `[148,12,[["o",1,"0.0305","3.0"]]]`)
+	if bid, _ := book.BestBid(); bid.Rate != MustParseAmount("0.0300") {
+		t.Fatalf("early message applied before the one it follows, best bid %+v", bid)
+	}
+	feed(t, p, `[148,11,[["o",1,"0.0305","1.0"]]]`)
+	feed(t, p, `[148,11,[["o",1,"0.0305","7.0"]]]`)
+	if bid, _ := book.BestBid(); bid.Rate != MustParseAmount("0.0305") || bid.Amount != MustParseAmount("3.0") {
+		t.Fatalf("messages applied out of order, best bid %+v", bid)
+	}
+
+	// 13 never arrives, and 15 comes after 14 has been held for longer than the window
+	feed(t, p, `[148,14,[["o",1,"0.0306","1.0"]]]`)
+	p.sequencesMutex.Lock()
+	held := p.sequences["148"].pending[14]
+	held.at = held.at.Add(-2 * p.reorderWindow)
+	p.sequences["148"].pending[14] = held
+	p.sequencesMutex.Unlock()
+	feed(t, p, `[148,15,[["o",1,"0.0307","1.0"]]]`)
+	mutex.Lock()
+	if len(resyncs) != 1 || resyncs[0].Expected != 13 || resyncs[0].Received != 15 {
+		t.Fatalf("unexpected resyncs %+v", resyncs)
+	}
+	mutex.Unlock()
+	if book.Ready() {
+		t.Fatal("book should wait for a new snapshot after a gap")
+	}
+
+	feed(t, p, `[148,40,[["i",{"currencyPair":"BTC_ETH","orderBook":[{"0.0320":"2.0"},{"0.0301":"1.0"}]}]]]`)
+	feed(t, p, `[148,41,[["o",0,"0.0319","1.0"]]]`)
+	if ask, _ := book.BestAsk(); !book.Ready() || ask.Rate != MustParseAmount("0.0319") {
+		t.Fatalf("book not rebuilt after resync, best ask %+v", ask)
+	}
+}
+
+func TestSequenceGapWithoutLaterMessage(t *testing.T) {
+	p := newWSTestClient(t)
+	p.reorderWindow = 10 * time.Millisecond
+	book, err := p.LiveOrderBook("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resyncs := make(chan WSResync, 1)
+	p.On("BTC_ETH-resync", func(r WSResync) {
+		resyncs <- r
+	})
+
+	feed(t, p, `[148,10,[["i",{"currencyPair":"BTC_ETH","orderBook":[{"0.0310":"2.0"},{"0.0300":"1.0"}]}]]]`)
+	// 11 is lost and the channel goes quiet after 12
+	feed(t, p, `[148,12,[["o",1,"0.0305","3.0"]]]`)
+	select {
+	case r := <-resyncs:
+		if r.Expected != 11 || r.Received != 12 || r.Pair != "BTC_ETH" {
+			t.Fatalf("unexpected resync %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("gap was not declared once the reorder window passed")
+	}
+	if book.Ready() {
+		t.Fatal("book should wait for a new snapshot after a gap")
+	}
+
+	// a gap filled inside the window does not resync
+	feed(t, p, `[148,20,[["i",{"currencyPair":"BTC_ETH","orderBook":[{"0.0310":"2.0"},{"0.0300":"1.0"}]}]]]`)
+	feed(t, p, `[148,22,[["o",1,"0.0305","3.0"]]]`)
+	feed(t, p, `[148,21,[["o",1,"0.0304","3.0"]]]`)
+	select {
+	case r := <-resyncs:
+		t.Fatalf("unexpected resync %+v", r)
+	case <-time.After(30 * time.Millisecond):
+	}
+}
@@ -2,7 +2,7 @@ package poloniex
 
 import (
 	"fmt"
-	"log"
+	"net/http"
 	"time"
 
 	"github.com/pkg/errors"
@@ -16,15 +16,15 @@ type (
 	//WSTicker describes a ticker item
 	WSTicker struct {
 		Pair          string
-		Last          float64
-		Ask           float64
-		Bid           float64
+		Last          Amount
+		Ask           Amount
+		Bid           Amount
 		PercentChange float64
-		BaseVolume    float64
-		QuoteVolume   float64
+		BaseVolume    Amount
+		QuoteVolume   Amount
 		IsFrozen      bool
-		DailyHigh     float64
-		DailyLow      float64
+		DailyHigh     Amount
+		DailyLow      Amount
 		PairID        int64
 	}
 
@@ -33,118 +33,337 @@ type (
 		Event   string
 		TradeID int64
 		Type    string
-		Rate    float64
-		Amount  float64
-		Total   float64
+		Rate    Amount
+		Amount  Amount
+		Total   Amount
 		TS      time.Time
+		// Book is the whole book for "snapshot" events, sent when the pair is subscribed
+		Book *OrderBook
 	}
 
 	WSReportFunc = func(time.Time)
+
+	// WSConnection is emitted with the "connected", "disconnected" and "reconnected" events
+	WSConnection struct {
+		Time time.Time
+		// Reconnects counts the connections made after the first
+		Reconnects int
+		// Err is the last read error before a disconnect, if there was one
+		Err error
+	}
+
+	// wsConn is the part of recws.RecConn the client uses
+	wsConn interface {
+		Dial(urlStr string, reqHeader http.Header)
+		ReadJSON(v interface{}) error
+		WriteMessage(messageType int, data []byte) error
+		IsConnected() bool
+		CloseAndReconnect()
+	}
 )
 
-func (p *Poloniex) StartWS() {
-	go func() {
-		for {
-			message := []interface{}{}
-			err := p.ws.ReadJSON(&message)
-			if err != nil {
-				log.Println("read:", err)
-				continue
-			}
-			chid := int64(message[0].(float64))
-			chids := toString(chid)
-			if chid > 100.0 && chid < 1000.0 {
-				// it's an orderbook
-				orderbook, err := p.parseOrderbook(message)
-				if err != nil {
-					log.Println(err)
-					continue
-				}
-				for _, v := range orderbook {
-					p.Emit(v.Event, v).Emit(v.Pair, v).Emit(v.Pair+"-"+v.Event, v)
-				}
-			} else if chids == p.ByName["ticker"] {
-				// it's a ticker
-				ticker, err := p.parseTicker(message)
-				if err != nil {
-					log.Printf("%s: (%s)\n", err, message)
-					continue
-				}
-				p.Emit("ticker", ticker)
+// wsPollInterval is how often a dropped connection is checked while recws reconnects
+const wsPollInterval = 100 * time.Millisecond
+
+// StartWS connects the websocket and starts emitting events. Subscriptions, including any made beforehand,
+// are sent each time the connection is made, so they carry on after recws reconnects.
+func (p *Poloniex) StartWS() error {
+	if err := p.ensureMarkets(); err != nil {
+		return err
+	}
+	p.ws.Dial(p.wsURI, http.Header{})
+	p.subscriptionsMutex.Lock()
+	p.wsStarted = true
+	p.subscriptionsMutex.Unlock()
+	go p.readWS()
+	go p.watchStale()
+	return nil
+}
+
+// readWS reads and handles messages, watching the connection so that lifecycle events are emitted
+// and subscriptions replayed whenever it comes back
+func (p *Poloniex) readWS() {
+	connected := false
+	connections := 0
+	var lastErr error
+	for {
+		if !p.ws.IsConnected() {
+			if connected {
+				connected = false
+				p.setConnected(false)
+				p.Emit("disconnected", WSConnection{Time: time.Now(), Reconnects: connections - 1, Err: lastErr})
 			}
+			time.Sleep(wsPollInterval)
+			continue
 		}
-	}()
+		if !connected {
+			connected = true
+			connections++
+			lastErr = nil
+			p.setConnected(true)
+			p.onConnect(connections - 1)
+		}
+
+		var frame interface{}
+		err := p.ws.ReadJSON(&frame)
+		if err != nil {
+			p.logger.Println("read:", err)
+			lastErr = err
+			continue
+		}
+		switch frame := frame.(type) {
+		case []interface{}:
+			p.handleWSMessage(frame)
+		case map[string]interface{}:
+			// replies to a bad command are an object, {"error": message}
+			p.wsError(WSError{Message: toString(frame["error"])})
+		}
+	}
 }
 
-func (p *Poloniex) Subscribe(chid string) error {
+// onConnect replays the subscriptions on a new connection, clearing the per channel state first if it is a reconnect
+func (p *Poloniex) onConnect(reconnects int) {
+	if reconnects > 0 {
+		p.resetChannels()
+	}
+	for _, chid := range p.subscribed() {
+		if err := p.sendSubscribe(chid); err != nil {
+			p.logger.Println("subscribe:", err)
+		}
+	}
+	event := WSConnection{Time: time.Now(), Reconnects: reconnects}
+	p.Emit("connected", event)
+	if reconnects > 0 {
+		p.Emit("reconnected", event)
+	}
+}
+
+// resetChannels forgets sequence numbers and empties the live books, the subscriptions replayed after a
+// reconnect send fresh snapshots
+func (p *Poloniex) resetChannels() {
+	p.sequencesMutex.Lock()
+	for _, cs := range p.sequences {
+		cs.stopExpiry()
+	}
+	p.sequences = map[string]*channelSequence{}
+	p.sequencesMutex.Unlock()
+
+	p.booksMutex.Lock()
+	defer p.booksMutex.Unlock()
+	for _, book := range p.books {
+		book.reset()
+	}
+}
+
+// subscribed returns the channels currently subscribed to
+func (p *Poloniex) subscribed() []string {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+	chids := make([]string, 0, len(p.subscriptions))
+	for chid := range p.subscriptions {
+		chids = append(chids, chid)
+	}
+	return chids
+}
+
+// started reports whether StartWS has been called, before then subscriptions are only recorded
+func (p *Poloniex) started() bool {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+	return p.wsStarted
+}
+
+func (p *Poloniex) isSubscribed(chid string) bool {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+	return p.subscriptions[chid]
+}
 
+// handleWSMessage turns a message read from the websocket into events
+func (p *Poloniex) handleWSMessage(message []interface{}) {
+	if len(message) == 0 {
+		return
+	}
+	chid := int64(toFloat(message[0]))
+	chids := toString(chid)
+	p.touch(chids)
+	if chids == heartbeatChannel {
+		return
+	}
+	if chid > 100.0 && chid < 1000.0 {
+		// it's an orderbook
+		orderbook, err := p.parseOrderbook(message)
+		if err != nil {
+			p.logger.Println(err)
+			return
+		}
+		if len(message) < 2 || message[1] == nil {
+			p.handleOrderbook(orderbook)
+			return
+		}
+		ready, resync := p.sequence(chids, int64(toFloat(message[1])), orderbook)
+		for _, updates := range ready {
+			p.handleOrderbook(updates)
+		}
+		if resync != nil {
+			p.resync(chids, *resync)
+		}
+	} else if chids == accountChannel {
+		p.handleAccount(message)
+	} else if chids == p.ByName["ticker"] {
+		// it's a ticker
+		ticker, err := p.parseTicker(message)
+		if err != nil {
+			p.logger.Printf("%s: (%s)\n", err, message)
+			return
+		}
+		p.Emit("ticker", ticker)
+	}
+}
+
+// wsError logs and emits an error reply from the exchange
+func (p *Poloniex) wsError(e WSError) {
+	p.logger.Printf("websocket error on channel %q: %s\n", e.Channel, e.Message)
+	p.Emit("ws-error", e)
+}
+
+// Subscribe adds a channel by name or id, if the websocket isn't started yet the subscription is sent by StartWS
+func (p *Poloniex) Subscribe(chid string) error {
+	if err := p.ensureMarkets(); err != nil {
+		return err
+	}
 	if c, ok := p.ByName[chid]; ok {
 		chid = c
-	} else if c, ok := p.ByID[chid]; ok {
-		chid = c
-	} else {
+	} else if _, ok := p.ByID[chid]; !ok {
 		return errors.New("unrecognised channelid in subscribe")
 	}
+	if chid == accountChannel {
+		if err := p.prepareAccount(); err != nil {
+			return err
+		}
+	}
 
+	p.subscriptionsMutex.Lock()
 	p.subscriptions[chid] = true
-	message := subscription{Command: "subscribe", Channel: chid}
-	return p.sendWSMessage(message)
+	started := p.wsStarted
+	p.subscriptionsMutex.Unlock()
+	if !started {
+		return nil
+	}
+	return p.sendWhileConnected(p.sendSubscribe(chid))
 }
 
+// Unsubscribe removes a channel by name or id
 func (p *Poloniex) Unsubscribe(chid string) error {
+	if err := p.ensureMarkets(); err != nil {
+		return err
+	}
 	if c, ok := p.ByName[chid]; ok {
 		chid = c
-	} else if c, ok := p.ByID[chid]; ok {
-		chid = c
-	} else {
-		return errors.New("unrecognised channelid in subscribe")
+	} else if _, ok := p.ByID[chid]; !ok {
+		return errors.New("unrecognised channelid in unsubscribe")
 	}
-	message := subscription{Command: "subscribe", Channel: chid}
+	message := subscription{Command: "unsubscribe", Channel: chid}
+	p.subscriptionsMutex.Lock()
 	delete(p.subscriptions, chid)
-	return p.sendWSMessage(message)
+	started := p.wsStarted
+	p.subscriptionsMutex.Unlock()
+	if !started {
+		return nil
+	}
+	return p.sendWhileConnected(p.sendWSMessage(message))
+}
+
+// sendWhileConnected passes on the error from sending a subscription change, unless the connection is down.
+// recws is then reconnecting and onConnect sends the subscriptions as they are now once it is back.
+func (p *Poloniex) sendWhileConnected(err error) error {
+	if err != nil && !p.ws.IsConnected() {
+		return nil
+	}
+	return err
+}
+
+// handleOrderbook applies one orderbook message to the live book and emits its events
+func (p *Poloniex) handleOrderbook(orderbook []WSOrderbook) {
+	p.updateLiveOrderBook(orderbook)
+	for _, v := range orderbook {
+		p.Emit(v.Event, v).Emit(v.Pair, v).Emit(v.Pair+"-"+v.Event, v)
+	}
 }
 
 func (p *Poloniex) parseTicker(raw []interface{}) (WSTicker, error) {
 	wt := WSTicker{}
-	var rawInner []interface{}
 	if len(raw) <= 2 {
 		return wt, errors.New("cannot parse to ticker")
 	}
-	rawInner = raw[2].([]interface{})
+	rawInner, ok := raw[2].([]interface{})
+	if !ok || len(rawInner) < 10 {
+		return wt, errors.New("cannot parse to ticker - invalid body")
+	}
 	marketID := int64(toFloat(rawInner[0]))
 	pair, ok := p.ByID[fmt.Sprintf("%d", marketID)]
 	if !ok {
 		return wt, errors.New("cannot parse to ticker - invalid marketID")
 	}
 
+	var f amountFields
 	wt.Pair = pair
 	wt.PairID = marketID
-	wt.Last = toFloat(rawInner[1])
-	wt.Ask = toFloat(rawInner[2])
-	wt.Bid = toFloat(rawInner[3])
+	wt.Last = f.parse(rawInner[1])
+	wt.Ask = f.parse(rawInner[2])
+	wt.Bid = f.parse(rawInner[3])
 	wt.PercentChange = toFloat(rawInner[4])
-	wt.BaseVolume = toFloat(rawInner[5])
-	wt.QuoteVolume = toFloat(rawInner[6])
+	wt.BaseVolume = f.parse(rawInner[5])
+	wt.QuoteVolume = f.parse(rawInner[6])
 	wt.IsFrozen = toFloat(rawInner[7]) != 0.0
-	wt.DailyHigh = toFloat(rawInner[8])
-	wt.DailyLow = toFloat(rawInner[9])
+	wt.DailyHigh = f.parse(rawInner[8])
+	wt.DailyLow = f.parse(rawInner[9])
+	if f.err != nil {
+		return wt, errors.Wrap(f.err, "cannot parse to ticker")
+	}
 
 	return wt, nil
 }
 
+// orderbookEntryLength is the fewest fields each kind of orderbook entry has,
+// ["i", body], ["o", side, rate, amount] and ["t", trade id, side, rate, amount, timestamp]
+var orderbookEntryLength = map[string]int{"i": 2, "o": 4, "t": 6}
+
 func (p *Poloniex) parseOrderbook(raw []interface{}) ([]WSOrderbook, error) {
 	trades := []WSOrderbook{}
+	var f amountFields
 	marketID := int64(toFloat(raw[0]))
 	pair, ok := p.ByID[fmt.Sprintf("%d", marketID)]
 	if !ok {
 		return trades, errors.New("cannot parse to orderbook - invalid marketID")
 	}
-	for _, _v := range raw[2].([]interface{}) {
-		v := _v.([]interface{})
+	if len(raw) < 3 {
+		return trades, errors.New("cannot parse to orderbook")
+	}
+	entries, ok := raw[2].([]interface{})
+	if !ok {
+		return trades, errors.New("cannot parse to orderbook - invalid body")
+	}
+	for _, e := range entries {
+		v, ok := e.([]interface{})
+		if !ok || len(v) == 0 {
+			return trades, errors.New("cannot parse to orderbook - invalid entry")
+		}
+		kind, _ := v[0].(string)
+		if len(v) < orderbookEntryLength[kind] {
+			return trades, errors.Errorf("cannot parse to orderbook - short %q entry", kind)
+		}
 		trade := WSOrderbook{}
 		trade.Pair = pair
-		switch v[0].(string) {
+		switch kind {
 		case "i":
+			book, err := parseBookSnapshot(v[1])
+			if err != nil {
+				return trades, err
+			}
+			trade.Event = "snapshot"
+			trade.Book = &book
+			trade.TS = time.Now()
 		case "o":
 			trade.Event = "modify"
 			if t := toFloat(v[3]); t == 0.0 {
@@ -154,23 +373,26 @@ func (p *Poloniex) parseOrderbook(raw []interface{}) ([]WSOrderbook, error) {
 			if t := toFloat(v[1]); t == 1.0 {
 				trade.Type = "bid"
 			}
-			trade.Rate = toFloat(v[2])
-			trade.Amount = toFloat(v[3])
+			trade.Rate = f.parse(v[2])
+			trade.Amount = f.parse(v[3])
 			trade.TS = time.Now()
 		case "t":
 			trade.Event = "trade"
-			trade.TradeID = int64(toFloat(raw[1]))
+			trade.TradeID = toInt64(v[1])
 			trade.Type = "sell"
 			if t := toFloat(v[2]); t == 1.0 {
 				trade.Type = "buy"
 			}
-			trade.Rate = toFloat(v[3])
-			trade.Amount = toFloat(v[4])
-			trade.Total = trade.Rate * trade.Amount
+			trade.Rate = f.parse(v[3])
+			trade.Amount = f.parse(v[4])
+			trade.Total = trade.Rate.Mul(trade.Amount)
 			t := time.Unix(int64(toFloat(v[5])), 0)
 			trade.TS = t
 		default:
 		}
+		if f.err != nil {
+			return trades, errors.Wrap(f.err, "cannot parse to orderbook")
+		}
 		trades = append(trades, trade)
 	}
 	return trades, nil
@@ -0,0 +1,197 @@
+package poloniex
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fakeWS stands in for recws.RecConn, test code controls whether it is connected and what it reads
+type fakeWS struct {
+	mutex     sync.Mutex
+	connected bool
+	written   []subscription
+	raw       []string
+	messages  chan string
+}
+
+func newFakeWS() *fakeWS {
+	return &fakeWS{messages: make(chan string, 10)}
+}
+
+func (f *fakeWS) Dial(string, http.Header) { f.setConnected(true) }
+
+func (f *fakeWS) ReadJSON(v interface{}) error {
+	select {
+	case raw := <-f.messages:
+		return json.Unmarshal([]byte(raw), v)
+	case <-time.After(10 * time.Millisecond):
+		if !f.IsConnected() {
+			return errors.New("websocket: not connected")
+		}
+		return errors.New("read timeout")
+	}
+}
+
+func (f *fakeWS) WriteMessage(_ int, data []byte) error {
+	var s subscription
+	json.Unmarshal(data, &s)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !f.connected {
+		// as recws does while it reconnects
+		return errors.New("websocket: not connected")
+	}
+	f.written = append(f.written, s)
+	f.raw = append(f.raw, string(data))
+	return nil
+}
+
+func (f *fakeWS) IsConnected() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.connected
+}
+
+func (f *fakeWS) CloseAndReconnect() {
+	f.setConnected(false)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		f.setConnected(true)
+	}()
+}
+
+func (f *fakeWS) setConnected(connected bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.connected = connected
+}
+
+// takeWritten returns and clears the messages written so far
+func (f *fakeWS) takeWritten() []subscription {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	written := f.written
+	f.written, f.raw = nil, nil
+	return written
+}
+
+// waitFor waits up to a second for event to be emitted
+func waitFor(t *testing.T, events chan string, event string) {
+	select {
+	case e := <-events:
+		if e != event {
+			t.Fatalf("got event %q, want %q", e, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %q", event)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	p := newWSTestClient(t)
+	ws := newFakeWS()
+	p.ws = ws
+	events := make(chan string, 10)
+	for _, e := range []string{"connected", "disconnected", "reconnected"} {
+		e := e
+		p.On(e, func(WSConnection) { events <- e })
+	}
+	if err := p.Subscribe("ticker"); err != nil {
+		t.Fatal(err)
+	}
+	book, err := p.LiveOrderBook("BTC_ETH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.StartWS(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, events, "connected")
+	if written := ws.takeWritten(); len(written) != 2 {
+		t.Fatalf("expected 2 subscriptions sent on connect, got %+v", written)
+	}
+	ws.messages <- `[148,5,[["i",{"currencyPair":"BTC_ETH","orderBook":[{"0.0310":"2.0"},{"0.0300":"1.0"}]}]]]`
+
+	ws.setConnected(false)
+	waitFor(t, events, "disconnected")
+	if !book.Ready() {
+		t.Fatal("snapshot was not applied")
+	}
+	ws.setConnected(true)
+	waitFor(t, events, "connected")
+	waitFor(t, events, "reconnected")
+	written := ws.takeWritten()
+	if len(written) != 2 || written[0].Command != "subscribe" || written[1].Command != "subscribe" {
+		t.Fatalf("expected subscriptions replayed, got %+v", written)
+	}
+	if book.Ready() {
+		t.Fatal("live book should wait for a new snapshot after a reconnect")
+	}
+
+	if err := p.Unsubscribe("ticker"); err != nil {
+		t.Fatal(err)
+	}
+	if written := ws.takeWritten(); len(written) != 1 || written[0].Command != "unsubscribe" || written[0].Channel != "1002" {
+		t.Fatalf("unexpected unsubscribe %+v", written)
+	}
+}
+
+func TestMalformedFrames(t *testing.T) {
+	p := newWSTestClient(t)
+	events := 0
+	for _, event := range []string{"modify", "remove", "trade", "snapshot", "ticker"} {
+		p.On(event, func(interface{}) {
+			events++
+		})
+	}
+	for _, raw := range []string{
+		`[148,1,"i"]`,
+		`[148,2,["o"]]`,
+		`[148,3,[[]]]`,
+		`[148,4,[[1,2,3]]]`,
+		`[148,5,[["o",1,"0.0300"]]]`,
+		`[148,6,[["t","126",0,"0.0310"]]]`,
+		`[148,7,[["i"]]]`,
+		`[1002,null,{"pair":148}]`,
+		`[1002,null,[148,"0.1"]]`,
+	} {
+		feed(t, p, raw)
+	}
+	if events != 0 {
+		t.Fatalf("expected malformed frames to be dropped, got %d events", events)
+	}
+}
+
+func TestSubscribeWhileReconnecting(t *testing.T) {
+	p := newWSTestClient(t)
+	ws := newFakeWS()
+	p.ws = ws
+	events := make(chan string, 10)
+	for _, e := range []string{"connected", "disconnected"} {
+		e := e
+		p.On(e, func(WSConnection) { events <- e })
+	}
+	if err := p.StartWS(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, events, "connected")
+
+	ws.setConnected(false)
+	waitFor(t, events, "disconnected")
+	if err := p.Subscribe("ticker"); err != nil {
+		t.Fatalf("subscribing while recws reconnects should be left to the replay, got %v", err)
+	}
+	if err := p.Unsubscribe("BTC_ETH"); err != nil {
+		t.Fatalf("unsubscribing while recws reconnects should be left to the replay, got %v", err)
+	}
+	ws.setConnected(true)
+	waitFor(t, events, "connected")
+	if written := ws.takeWritten(); len(written) != 1 || written[0].Channel != "1002" || written[0].Command != "subscribe" {
+		t.Fatalf("expected the subscription replayed on reconnect, got %+v", written)
+	}
+}